@@ -0,0 +1,461 @@
+package main
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/quintans/faults"
+	"github.com/quintans/goSQL/db"
+	"github.com/quintans/goSQL/dbx"
+)
+
+// HEADER marks the files written by the generator. Files without it are never overwritten.
+const HEADER = "// Code generated by gosql-gen. DO NOT EDIT."
+
+// Config holds the options of the generator
+type Config struct {
+	Package string
+	// Include has the name patterns, as in path.Match, of the tables to generate. Empty includes all.
+	Include []string
+	// Exclude has the name patterns of the tables not to generate
+	Exclude []string
+	// StripPrefix is removed from the table names when naming the structs
+	StripPrefix string
+	// Singular removes the plural of the table names when naming the structs
+	Singular bool
+	// Version is the name of the column used for optimistic locking
+	Version string
+}
+
+type entity struct {
+	table    *db.CatalogTable
+	Var      string
+	Type     string
+	receiver string
+	fields   []*field
+	assocs   []*assoc
+	// names of the struct fields, to avoid collisions
+	names map[string]bool
+}
+
+type field struct {
+	column  *db.CatalogColumn
+	Var     string
+	Name    string
+	GoType  string
+	key     bool
+	version bool
+}
+
+type assoc struct {
+	Var    string
+	Name   string
+	GoType string
+	expr   string
+}
+
+type generator struct {
+	config   Config
+	entities []*entity
+	byName   map[string]*entity
+}
+
+// Generate returns the Go source with the table mappings and the entity structs of the catalog tables
+func Generate(config Config, tables []*db.CatalogTable) ([]byte, error) {
+	g := &generator{config: config, byName: map[string]*entity{}}
+	for _, table := range tables {
+		if g.accept(table.Name) {
+			g.add(table)
+		}
+	}
+	sort.Slice(g.entities, func(i, j int) bool {
+		return g.entities[i].Var < g.entities[j].Var
+	})
+	for _, e := range g.entities {
+		g.associate(e)
+	}
+
+	src := g.render()
+	formatted, err := format.Source(src)
+	if err != nil {
+		return nil, faults.Errorf("formatting the generated source: %w\n%s", err, src)
+	}
+	return formatted, nil
+}
+
+func (g *generator) accept(table string) bool {
+	name := strings.ToUpper(table)
+	matches := func(patterns []string) bool {
+		for _, p := range patterns {
+			if ok, _ := path.Match(strings.ToUpper(p), name); ok {
+				return true
+			}
+		}
+		return false
+	}
+	return (len(g.config.Include) == 0 || matches(g.config.Include)) && !matches(g.config.Exclude)
+}
+
+func (g *generator) add(table *db.CatalogTable) {
+	e := &entity{
+		table: table,
+		Var:   identifier(strings.ToUpper(table.Name)),
+		Type:  g.typeName(table.Name),
+		names: map[string]bool{},
+	}
+	e.receiver = strings.ToLower(e.Type[:1])
+
+	keys := map[string]bool{}
+	for _, k := range table.Keys {
+		keys[strings.ToUpper(k)] = true
+	}
+	for _, c := range table.Columns {
+		f := &field{
+			column:  c,
+			Var:     e.Var + "_C_" + identifier(strings.ToUpper(c.Name)),
+			Name:    dbx.ToCamelCase(c.Name),
+			key:     keys[strings.ToUpper(c.Name)],
+			version: strings.EqualFold(c.Name, g.config.Version) && isInteger(c.Type),
+		}
+		f.GoType = goType(f)
+		e.names[f.Name] = true
+		e.fields = append(e.fields, f)
+	}
+
+	g.entities = append(g.entities, e)
+	g.byName[strings.ToUpper(table.Name)] = e
+}
+
+func (g *generator) typeName(table string) string {
+	name := table
+	if p := g.config.StripPrefix; p != "" && len(name) > len(p) && strings.EqualFold(name[:len(p)], p) {
+		name = name[len(p):]
+	}
+	name = dbx.ToCamelCase(name)
+	if g.config.Singular {
+		name = singular(name)
+	}
+	return name
+}
+
+// associate adds the associations of the foreign keys of the entity.
+// Join tables, with two foreign keys covering all of their columns, become many to many associations.
+func (g *generator) associate(e *entity) {
+	fks := []*db.CatalogForeignKey{}
+	for _, fk := range e.table.ForeignKeys {
+		if g.byName[strings.ToUpper(fk.TableTo)] != nil {
+			fks = append(fks, fk)
+		}
+	}
+
+	if g.isJoinTable(e, fks) {
+		a := g.byName[strings.ToUpper(fks[0].TableTo)]
+		b := g.byName[strings.ToUpper(fks[1].TableTo)]
+		g.addM2M(a, b, e, fks[0], fks[1])
+		if a != b {
+			g.addM2M(b, a, e, fks[1], fks[0])
+		}
+		return
+	}
+
+	for _, fk := range fks {
+		to := g.byName[strings.ToUpper(fk.TableTo)]
+		from := e.columnVars(fk.Columns)
+		target := to.columnVars(fk.ColumnsTo)
+
+		name := to.Type
+		if len(fk.Columns) == 1 {
+			col := strings.ToUpper(fk.Columns[0])
+			if strings.HasSuffix(col, "_ID") && len(col) > 3 {
+				name = dbx.ToCamelCase(col[:len(col)-3])
+			}
+		}
+		e.addAssoc(name, "*"+to.Type, from, target)
+
+		// the other side
+		reverse := plural(e.Type)
+		if name != to.Type {
+			reverse = name + reverse
+		}
+		to.addAssoc(reverse, "[]*"+e.Type, target, from)
+	}
+}
+
+func (g *generator) isJoinTable(e *entity, fks []*db.CatalogForeignKey) bool {
+	if len(fks) != 2 || len(e.table.Keys) != len(e.table.Columns) {
+		return false
+	}
+	covered := map[string]bool{}
+	for _, fk := range fks {
+		for _, c := range fk.Columns {
+			covered[strings.ToUpper(c)] = true
+		}
+	}
+	for _, k := range e.table.Keys {
+		if !covered[strings.ToUpper(k)] {
+			return false
+		}
+	}
+	return len(covered) == len(e.table.Keys)
+}
+
+// addM2M adds to a the many to many association with b, through the join entity
+func (g *generator) addM2M(a, b, join *entity, fkA, fkB *db.CatalogForeignKey) {
+	name := a.uniqueName(plural(b.Type))
+	a.assocs = append(a.assocs, &assoc{
+		Var:    a.Var + "_A_" + dbx.ToSnakeCase(name),
+		Name:   name,
+		GoType: "[]*" + b.Type,
+		expr: fmt.Sprintf("db.NewM2MAssociation(\n%q,\ndb.ASSOCIATE(%s).WITH(%s),\ndb.ASSOCIATE(%s).WITH(%s),\n)",
+			name,
+			strings.Join(a.columnVars(fkA.ColumnsTo), ", "), strings.Join(join.columnVars(fkA.Columns), ", "),
+			strings.Join(join.columnVars(fkB.Columns), ", "), strings.Join(b.columnVars(fkB.ColumnsTo), ", ")),
+	})
+}
+
+func (e *entity) addAssoc(name string, goType string, from []string, to []string) {
+	name = e.uniqueName(name)
+	e.assocs = append(e.assocs, &assoc{
+		Var:    e.Var + "_A_" + dbx.ToSnakeCase(name),
+		Name:   name,
+		GoType: goType,
+		expr: fmt.Sprintf("%s.\nASSOCIATE(%s).\nTO(%s).\nAs(%q)",
+			e.Var, strings.Join(from, ", "), strings.Join(to, ", "), name),
+	})
+}
+
+func (e *entity) uniqueName(name string) string {
+	unique := name
+	for k := 2; e.names[unique]; k++ {
+		unique = fmt.Sprintf("%s%d", name, k)
+	}
+	e.names[unique] = true
+	return unique
+}
+
+func (e *entity) columnVars(columns []string) []string {
+	vars := make([]string, len(columns))
+	for k, c := range columns {
+		vars[k] = e.Var + "_C_" + identifier(strings.ToUpper(c))
+	}
+	return vars
+}
+
+func (g *generator) render() []byte {
+	sb := &strings.Builder{}
+	w := func(format string, args ...interface{}) {
+		fmt.Fprintf(sb, format, args...)
+	}
+
+	w("%s\n\npackage %s\n\nimport (\n", HEADER, g.config.Package)
+	if g.uses("time.Time") {
+		w("\"time\"\n\n")
+	}
+	w("\"github.com/quintans/goSQL/db\"\n)\n")
+
+	for _, e := range g.entities {
+		w("\n// %s\n\n", e.Var)
+
+		setters := []*field{}
+		for _, f := range e.fields {
+			if !f.key && !f.version {
+				setters = append(setters, f)
+			}
+		}
+
+		w("type %s struct {\n", e.Type)
+		if len(setters) > 0 {
+			w("db.Marker\n\n")
+		}
+		for _, f := range e.fields {
+			w("%s %s\n", f.Name, f.GoType)
+		}
+		if len(e.assocs) > 0 {
+			w("\n")
+		}
+		for _, a := range e.assocs {
+			w("%s %s\n", a.Name, a.GoType)
+		}
+		w("}\n")
+
+		if e.Type != dbx.ToCamelCase(e.table.Name) {
+			w("\nfunc (%s *%s) TableName() string {\nreturn %q\n}\n", e.receiver, e.Type, dbx.ToCamelCase(e.table.Name))
+		}
+
+		for _, f := range setters {
+			param := lowerFirst(f.Name)
+			if token.IsKeyword(param) || param == e.receiver {
+				param = "value"
+			}
+			w("\nfunc (%s *%s) Set%s(%s %s) {\n%s.%s = %s\n%s.Mark(%q)\n}\n",
+				e.receiver, e.Type, f.Name, param, f.GoType, e.receiver, f.Name, param, e.receiver, f.Name)
+		}
+
+		w("\nvar (\n%s = db.TABLE(%q)\n", e.Var, e.table.Name)
+		for _, f := range e.fields {
+			w("%s = %s\n", f.Var, columnExpr(e, f))
+		}
+		for _, a := range e.assocs {
+			w("\n%s = %s\n", a.Var, a.expr)
+		}
+		w(")\n")
+	}
+	return []byte(sb.String())
+}
+
+func (g *generator) uses(goType string) bool {
+	for _, e := range g.entities {
+		for _, f := range e.fields {
+			if strings.TrimPrefix(f.GoType, "*") == goType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func columnExpr(e *entity, f *field) string {
+	switch {
+	case f.version:
+		return fmt.Sprintf("%s.VERSION(%q)", e.Var, f.column.Name)
+	case f.key:
+		return fmt.Sprintf("%s.KEY(%q)%s", e.Var, f.column.Name, typeExpr(f.column.Type))
+	}
+	expr := fmt.Sprintf("%s.COLUMN(%q)%s", e.Var, f.column.Name, typeExpr(f.column.Type))
+	if f.column.Nullable {
+		expr += ".Nullable()"
+	}
+	return expr
+}
+
+// typeExpr returns the declaration of the column type, or an empty string if the type is unknown or incomplete
+func typeExpr(t db.ColumnType) string {
+	switch t.Kind {
+	case db.TYPE_CHAR:
+		if t.Length > 0 {
+			return fmt.Sprintf(".Type(db.Char(%d))", t.Length)
+		}
+	case db.TYPE_VARCHAR:
+		if t.Length > 0 {
+			return fmt.Sprintf(".Type(db.Varchar(%d))", t.Length)
+		}
+		return ".Type(db.Text())"
+	case db.TYPE_DECIMAL:
+		if t.Precision > 0 {
+			return fmt.Sprintf(".Type(db.Decimal(%d, %d))", t.Precision, t.Scale)
+		}
+	case db.TYPE_TEXT:
+		return ".Type(db.Text())"
+	case db.TYPE_SMALLINT:
+		return ".Type(db.Smallint())"
+	case db.TYPE_INTEGER:
+		return ".Type(db.Integer())"
+	case db.TYPE_BIGINT:
+		return ".Type(db.Bigint())"
+	case db.TYPE_DOUBLE:
+		return ".Type(db.Double())"
+	case db.TYPE_BOOLEAN:
+		return ".Type(db.Boolean())"
+	case db.TYPE_DATE:
+		return ".Type(db.Date())"
+	case db.TYPE_TIMESTAMP:
+		return ".Type(db.Timestamp())"
+	case db.TYPE_BINARY:
+		return ".Type(db.Binary())"
+	}
+	return ""
+}
+
+// goType returns the type of the struct field. Keys and nullable columns are pointers.
+func goType(f *field) string {
+	var typ string
+	t := f.column.Type
+	switch t.Kind {
+	case db.TYPE_CHAR, db.TYPE_VARCHAR, db.TYPE_TEXT:
+		typ = "string"
+	case db.TYPE_SMALLINT, db.TYPE_INTEGER, db.TYPE_BIGINT:
+		typ = "int64"
+	case db.TYPE_DECIMAL:
+		if t.Scale == 0 && t.Precision > 0 && t.Precision <= 18 {
+			typ = "int64"
+		} else {
+			typ = "float64"
+		}
+	case db.TYPE_DOUBLE:
+		typ = "float64"
+	case db.TYPE_BOOLEAN:
+		typ = "bool"
+	case db.TYPE_DATE, db.TYPE_TIMESTAMP:
+		typ = "time.Time"
+	case db.TYPE_BINARY:
+		return "[]byte"
+	default:
+		return "interface{}"
+	}
+	if f.version {
+		return typ
+	}
+	if f.key || f.column.Nullable {
+		return "*" + typ
+	}
+	return typ
+}
+
+func isInteger(t db.ColumnType) bool {
+	switch t.Kind {
+	case db.TYPE_SMALLINT, db.TYPE_INTEGER, db.TYPE_BIGINT:
+		return true
+	case db.TYPE_DECIMAL:
+		return t.Scale == 0
+	}
+	return false
+}
+
+// identifier replaces the characters that cannot be part of a Go identifier
+func identifier(name string) string {
+	id := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+	if id == "" || unicode.IsDigit(rune(id[0])) {
+		id = "_" + id
+	}
+	return id
+}
+
+func lowerFirst(name string) string {
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+func plural(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, "s") || strings.HasSuffix(lower, "x") ||
+		strings.HasSuffix(lower, "ch") || strings.HasSuffix(lower, "sh"):
+		return name + "es"
+	case strings.HasSuffix(lower, "y") && len(lower) > 1 && !strings.ContainsRune("aeiou", rune(lower[len(lower)-2])):
+		return name[:len(name)-1] + "ies"
+	}
+	return name + "s"
+}
+
+func singular(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, "ies") && len(lower) > 3:
+		return name[:len(name)-3] + "y"
+	case strings.HasSuffix(lower, "sses") || strings.HasSuffix(lower, "xes") ||
+		strings.HasSuffix(lower, "ches") || strings.HasSuffix(lower, "shes"):
+		return name[:len(name)-2]
+	case strings.HasSuffix(lower, "s") && !strings.HasSuffix(lower, "ss") && len(lower) > 1:
+		return name[:len(name)-1]
+	}
+	return name
+}
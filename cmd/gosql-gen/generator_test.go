@@ -0,0 +1,180 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/quintans/goSQL/db"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// golden compares the generated source with the golden file, rewriting it with -update
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	file := filepath.Join("testdata", name+".golden")
+	if *update {
+		require.NoError(t, os.WriteFile(file, got, 0o644))
+	}
+	want, err := os.ReadFile(file)
+	require.NoError(t, err)
+	require.Equal(t, string(want), string(got))
+}
+
+func catalog() []*db.CatalogTable {
+	column := func(name string, typ db.ColumnType, nullable bool) *db.CatalogColumn {
+		return &db.CatalogColumn{Name: name, Type: typ, Nullable: nullable}
+	}
+	return []*db.CatalogTable{
+		{
+			Name: "APP_PUBLISHERS",
+			Columns: []*db.CatalogColumn{
+				column("ID", db.Bigint(), false),
+				column("VERSION", db.Integer(), false),
+				column("NAME", db.Varchar(50), true),
+			},
+			Keys: []string{"ID"},
+		},
+		{
+			Name: "APP_BOOKS",
+			Columns: []*db.CatalogColumn{
+				column("ID", db.Bigint(), false),
+				column("VERSION", db.Integer(), false),
+				column("TITLE", db.Varchar(100), false),
+				column("PRICE", db.Decimal(10, 2), true),
+				column("PUBLISHED", db.Timestamp(), true),
+				column("PUBLISHER_ID", db.Bigint(), true),
+			},
+			Keys: []string{"ID"},
+			ForeignKeys: []*db.CatalogForeignKey{
+				{Name: "FK_BOOK_PUBLISHER", Columns: []string{"PUBLISHER_ID"}, TableTo: "APP_PUBLISHERS", ColumnsTo: []string{"ID"}},
+			},
+		},
+		{
+			Name: "APP_AUTHORS",
+			Columns: []*db.CatalogColumn{
+				column("ID", db.Bigint(), false),
+				column("NAME", db.Varchar(50), false),
+			},
+			Keys: []string{"ID"},
+		},
+		{
+			Name: "APP_AUTHOR_BOOKS",
+			Columns: []*db.CatalogColumn{
+				column("AUTHOR_ID", db.Bigint(), false),
+				column("BOOK_ID", db.Bigint(), false),
+			},
+			Keys: []string{"AUTHOR_ID", "BOOK_ID"},
+			ForeignKeys: []*db.CatalogForeignKey{
+				{Name: "FK_AB_AUTHOR", Columns: []string{"AUTHOR_ID"}, TableTo: "APP_AUTHORS", ColumnsTo: []string{"ID"}},
+				{Name: "FK_AB_BOOK", Columns: []string{"BOOK_ID"}, TableTo: "APP_BOOKS", ColumnsTo: []string{"ID"}},
+			},
+		},
+		{
+			Name: "GOSQL_SCHEMA_HISTORY",
+			Columns: []*db.CatalogColumn{
+				column("VERSION", db.Bigint(), false),
+				column("NAME", db.Varchar(255), false),
+			},
+			Keys: []string{"VERSION"},
+		},
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      Config
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:   "default",
+			config: Config{Package: "entities", Version: "VERSION"},
+			contains: []string{
+				"type AppBooks struct",
+				"APP_BOOKS_A_PUBLISHER = APP_BOOKS.",
+				"APP_BOOKS_A_APP_AUTHORSES = db.NewM2MAssociation(",
+				"type GosqlSchemaHistory struct",
+			},
+		},
+		{
+			name: "strip_prefix_singular",
+			config: Config{
+				Package:     "model",
+				Exclude:     []string{"GOSQL_SCHEMA_*"},
+				StripPrefix: "APP_",
+				Singular:    true,
+				Version:     "VERSION",
+			},
+			contains: []string{
+				"type Book struct",
+				"func (b *Book) TableName() string",
+				"db.NewM2MAssociation(",
+				"Books []*Book",
+			},
+			notContains: []string{"GosqlSchemaHistory"},
+		},
+		{
+			name: "include_exclude",
+			config: Config{
+				Package:     "model",
+				Include:     []string{"app_*"},
+				Exclude:     []string{"APP_AUTHOR*"},
+				StripPrefix: "APP_",
+				Singular:    true,
+				Version:     "VERSION",
+			},
+			contains:    []string{"type Publisher struct", "type Book struct"},
+			notContains: []string{"Author", "GosqlSchemaHistory", "NewM2MAssociation"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := Generate(tt.config, catalog())
+			require.NoError(t, err)
+			for _, s := range tt.contains {
+				require.Contains(t, string(src), s)
+			}
+			for _, s := range tt.notContains {
+				require.NotContains(t, string(src), s)
+			}
+			golden(t, tt.name, src)
+		})
+	}
+}
+
+func TestCheckGenerated(t *testing.T) {
+	dir := t.TempDir()
+
+	missing := filepath.Join(dir, "missing.go")
+	require.NoError(t, checkGenerated(missing))
+
+	generated := filepath.Join(dir, "generated.go")
+	require.NoError(t, os.WriteFile(generated, []byte(HEADER+"\n\npackage model\n"), 0o644))
+	require.NoError(t, checkGenerated(generated))
+
+	// hand written files are never overwritten
+	handWritten := filepath.Join(dir, "model.go")
+	content := []byte("package model\n\ntype Book struct{}\n")
+	require.NoError(t, os.WriteFile(handWritten, content, 0o644))
+	require.Error(t, checkGenerated(handWritten))
+
+	err := runStructs(dir, handWritten)
+	require.Error(t, err)
+	data, err := os.ReadFile(handWritten)
+	require.NoError(t, err)
+	require.Equal(t, content, data)
+}
+
+func TestGenerateFromStructs(t *testing.T) {
+	src, err := GenerateFromStructs(filepath.Join("testdata", "model"))
+	require.NoError(t, err)
+	require.NotContains(t, string(src), "SUMMARY")
+	require.NotContains(t, string(src), "IGNORED")
+	golden(t, "structs", src)
+}
@@ -0,0 +1,149 @@
+// Command gosql-gen generates the goSQL table mappings, and the matching entity structs, from the catalog of an existing database.
+// With -structs, it generates instead the table mappings of the tagged structs of a package.
+//
+// Usage:
+//
+//	gosql-gen -driver postgres -dsn "dbname=app user=app sslmode=disable" -package model -out model/entities_gen.go
+//	gosql-gen -structs model -out model/mappings_gen.go
+//
+// The generated file starts with a header marking it as generated, and only files with that header are overwritten,
+// so hand written code, like triggers or String methods, must go to other files of the package.
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/quintans/faults"
+	"github.com/quintans/goSQL/db"
+	"github.com/quintans/goSQL/migrate"
+	"github.com/quintans/goSQL/translators"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/nakagami/firebirdsql"
+	_ "gopkg.in/goracle.v2"
+)
+
+var translatorsByDriver = map[string]func() db.Translator{
+	"postgres":    func() db.Translator { return translators.NewPostgreSQLTranslator() },
+	"mysql":       func() db.Translator { return translators.NewMySQL5Translator() },
+	"firebirdsql": func() db.Translator { return translators.NewFirebirdSQLTranslator() },
+	"goracle":     func() db.Translator { return translators.NewOracleTranslator() },
+}
+
+func main() {
+	driver := flag.String("driver", "postgres", "database driver: postgres, mysql, firebirdsql or goracle")
+	dsn := flag.String("dsn", "", "data source name")
+	out := flag.String("out", "entities_gen.go", "generated file, or - for the standard output")
+	pkg := flag.String("package", "entities", "package of the generated file")
+	include := flag.String("include", "", "comma separated patterns of the tables to generate, like BOOK*")
+	exclude := flag.String("exclude", migrate.HISTORY_TABLE+","+migrate.LOCK_TABLE, "comma separated patterns of the tables not to generate")
+	stripPrefix := flag.String("strip-prefix", "", "prefix of the table names removed from the struct names")
+	singular := flag.Bool("singular", false, "use the singular of the table names for the struct names")
+	version := flag.String("version", "VERSION", "name of the optimistic locking column")
+	structs := flag.String("structs", "", "directory of the package with the tagged structs to generate the mappings from, instead of the database")
+	flag.Parse()
+
+	if *structs != "" {
+		if err := runStructs(*structs, *out); err != nil {
+			fmt.Fprintf(os.Stderr, "gosql-gen: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	config := Config{
+		Package:     *pkg,
+		Include:     split(*include),
+		Exclude:     split(*exclude),
+		StripPrefix: *stripPrefix,
+		Singular:    *singular,
+		Version:     *version,
+	}
+	if err := run(*driver, *dsn, *out, config); err != nil {
+		fmt.Fprintf(os.Stderr, "gosql-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(driver, dsn, out string, config Config) error {
+	newTranslator, ok := translatorsByDriver[driver]
+	if !ok {
+		return faults.Errorf("unknown driver %s", driver)
+	}
+	if out != "-" {
+		if err := checkGenerated(out); err != nil {
+			return err
+		}
+	}
+
+	database, err := sql.Open(driver, dsn)
+	if err != nil {
+		return faults.Wrap(err)
+	}
+	defer database.Close()
+
+	translator := newTranslator()
+	tm := db.NewTransactionManager(database, translator)
+	tables, err := translator.ReadCatalog(tm.Store())
+	if err != nil {
+		return faults.Wrap(err)
+	}
+
+	src, err := Generate(config, tables)
+	if err != nil {
+		return err
+	}
+	return write(out, src)
+}
+
+func runStructs(dir, out string) error {
+	if out != "-" {
+		if err := checkGenerated(out); err != nil {
+			return err
+		}
+	}
+	src, err := GenerateFromStructs(dir)
+	if err != nil {
+		return err
+	}
+	return write(out, src)
+}
+
+func write(out string, src []byte) error {
+	if out == "-" {
+		_, err := os.Stdout.Write(src)
+		return faults.Wrap(err)
+	}
+	return faults.Wrap(os.WriteFile(out, src, 0o644))
+}
+
+// checkGenerated fails if the file exists and was not written by the generator
+func checkGenerated(file string) error {
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return faults.Wrap(err)
+	}
+	if !bytes.HasPrefix(data, []byte(HEADER)) {
+		return faults.Errorf("%s was not generated by gosql-gen and will not be overwritten", file)
+	}
+	return nil
+}
+
+func split(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
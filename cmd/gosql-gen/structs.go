@@ -0,0 +1,291 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/quintans/faults"
+	"github.com/quintans/goSQL/db"
+	"github.com/quintans/goSQL/dbx"
+)
+
+// mapped is a struct with, at least, one field tagged with `sql:"key"`
+type mapped struct {
+	Name    string
+	Var     string
+	columns []*mappedColumn
+	// fields referencing other structs, that can become associations
+	refs []*ast.Field
+	vars []string
+}
+
+type mappedColumn struct {
+	Field string
+	Var   string
+	Name  string
+	tag   db.FieldTag
+}
+
+type structsGenerator struct {
+	pkg     string
+	structs map[string]*ast.StructType
+	mapped  []*mapped
+	byName  map[string]*mapped
+}
+
+// GenerateFromStructs returns the Go source with the table mappings of the structs of the package in the directory.
+//
+// The structs with a field tagged with `sql:"key"` are mapped to a table with the upper snake case of their name,
+// and their fields to columns in the same way, unless a name is set with `sql:"column=NAME"`.
+// Fields tagged with `sql:"version"` are mapped to the version column.
+// Fields tagged with `sql:"fk=Other"` reference the key of the struct Other, becoming an association
+// named after the field of type *Other, or Other if there is none.
+// A field of type []Struct in Other, becomes the association of the other side.
+func GenerateFromStructs(dir string) ([]byte, error) {
+	g := &structsGenerator{structs: map[string]*ast.StructType{}, byName: map[string]*mapped{}}
+	if err := g.parse(dir); err != nil {
+		return nil, err
+	}
+
+	embedded := map[string]bool{}
+	for _, st := range g.structs {
+		for _, f := range st.Fields.List {
+			if len(f.Names) == 0 {
+				embedded[localType(f.Type)] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(g.structs))
+	for name := range g.structs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if embedded[name] {
+			continue
+		}
+		m := &mapped{Name: name, Var: dbx.ToSnakeCase(name)}
+		if err := g.collect(m, g.structs[name], map[string]bool{}); err != nil {
+			return nil, err
+		}
+		if m.hasKey() {
+			g.mapped = append(g.mapped, m)
+			g.byName[name] = m
+		}
+	}
+
+	for _, m := range g.mapped {
+		if err := g.associate(m); err != nil {
+			return nil, err
+		}
+	}
+
+	src := g.render()
+	formatted, err := format.Source(src)
+	if err != nil {
+		return nil, faults.Errorf("formatting the generated source: %w\n%s", err, src)
+	}
+	return formatted, nil
+}
+
+func (g *structsGenerator) parse(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return faults.Wrap(err)
+	}
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return faults.Wrap(err)
+		}
+		if strings.HasPrefix(string(data), HEADER) {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, data, 0)
+		if err != nil {
+			return faults.Wrap(err)
+		}
+		g.pkg = f.Name.Name
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if st, ok := ts.Type.(*ast.StructType); ok {
+					g.structs[ts.Name.Name] = st
+				}
+			}
+		}
+	}
+	if g.pkg == "" {
+		return faults.Errorf("no Go files in %s", dir)
+	}
+	return nil
+}
+
+// collect gathers the columns of the struct, including the ones of embedded structs
+func (g *structsGenerator) collect(m *mapped, st *ast.StructType, visiting map[string]bool) error {
+	for _, f := range st.Fields.List {
+		tag := db.ParseFieldTag(fieldTag(f))
+		name := localType(f.Type)
+
+		if len(f.Names) == 0 || tag.Embedded {
+			// embedded structs of other packages, like db.Marker, are not mapped
+			if inner, ok := g.structs[name]; ok && !visiting[name] {
+				visiting[name] = true
+				if err := g.collect(m, inner, visiting); err != nil {
+					return err
+				}
+				delete(visiting, name)
+			}
+			continue
+		}
+
+		for _, ident := range f.Names {
+			if !ident.IsExported() {
+				continue
+			}
+			if _, ok := g.structs[name]; ok {
+				m.refs = append(m.refs, f)
+				continue
+			}
+			column := tag.Column
+			if column == "" {
+				column = dbx.ToSnakeCase(ident.Name)
+			}
+			m.columns = append(m.columns, &mappedColumn{
+				Field: ident.Name,
+				Var:   m.Var + "_C_" + identifier(strings.ToUpper(column)),
+				Name:  column,
+				tag:   tag,
+			})
+		}
+	}
+	return nil
+}
+
+func (m *mapped) hasKey() bool {
+	return len(m.keys()) > 0
+}
+
+func (m *mapped) keys() []*mappedColumn {
+	keys := []*mappedColumn{}
+	for _, c := range m.columns {
+		if c.tag.Key {
+			keys = append(keys, c)
+		}
+	}
+	return keys
+}
+
+// refTo returns the name of the first field referencing the struct, as *Struct or []Struct
+func (m *mapped) refTo(name string, slice bool) string {
+	for _, f := range m.refs {
+		_, isSlice := f.Type.(*ast.ArrayType)
+		if isSlice == slice && localType(f.Type) == name {
+			return f.Names[0].Name
+		}
+	}
+	return ""
+}
+
+func (g *structsGenerator) associate(m *mapped) error {
+	for _, c := range m.columns {
+		if c.tag.FK == "" {
+			continue
+		}
+		to := g.byName[c.tag.FK]
+		if to == nil {
+			return faults.Errorf("%s.%s references %s, that is not a struct with a key", m.Name, c.Field, c.tag.FK)
+		}
+		keys := to.keys()
+		if len(keys) != 1 {
+			return faults.Errorf("%s.%s references %s, that does not have a single key", m.Name, c.Field, c.tag.FK)
+		}
+
+		alias := m.refTo(to.Name, false)
+		if alias == "" {
+			alias = to.Name
+		}
+		m.vars = append(m.vars, fmt.Sprintf("%s_A_%s = %s.\nASSOCIATE(%s).\nTO(%s).\nAs(%q)",
+			m.Var, dbx.ToSnakeCase(alias), m.Var, c.Var, keys[0].Var, alias))
+
+		if reverse := to.refTo(m.Name, true); reverse != "" {
+			to.vars = append(to.vars, fmt.Sprintf("%s_A_%s = %s.\nASSOCIATE(%s).\nTO(%s).\nAs(%q)",
+				to.Var, dbx.ToSnakeCase(reverse), to.Var, keys[0].Var, c.Var, reverse))
+		}
+	}
+	return nil
+}
+
+func (g *structsGenerator) render() []byte {
+	sb := &strings.Builder{}
+	w := func(format string, args ...interface{}) {
+		fmt.Fprintf(sb, format, args...)
+	}
+
+	w("%s\n\npackage %s\n\nimport \"github.com/quintans/goSQL/db\"\n", HEADER, g.pkg)
+	for _, m := range g.mapped {
+		w("\n// %s\n\nvar (\n%s = db.TABLE(%q)\n", m.Var, m.Var, m.Var)
+		for _, c := range m.columns {
+			fn := "COLUMN"
+			switch {
+			case c.tag.Key:
+				fn = "KEY"
+			case c.tag.Version:
+				fn = "VERSION"
+			}
+			w("%s = %s.%s(%q)", c.Var, m.Var, fn, c.Name)
+			if dbx.ToCamelCase(c.Name) != c.Field {
+				// the column is mapped to the field by its alias
+				w(".As(%q)", c.Field)
+			}
+			w("\n")
+		}
+		for _, v := range m.vars {
+			w("\n%s\n", v)
+		}
+		w(")\n")
+	}
+	return []byte(sb.String())
+}
+
+func fieldTag(f *ast.Field) string {
+	if f.Tag == nil {
+		return ""
+	}
+	tag, err := strconv.Unquote(f.Tag.Value)
+	if err != nil {
+		return ""
+	}
+	return reflect.StructTag(tag).Get("sql")
+}
+
+// localType returns the name of the type, declared in the same package, of the field, ignoring pointers and slices
+func localType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return localType(t.X)
+	case *ast.ArrayType:
+		return localType(t.Elt)
+	}
+	return ""
+}
@@ -0,0 +1,36 @@
+package model
+
+import "github.com/quintans/goSQL/db"
+
+type Entity struct {
+	ID      *int64 `sql:"key"`
+	Version int64  `sql:"version"`
+}
+
+type Publisher struct {
+	db.Marker
+	Entity
+
+	Name  *string `sql:"column=PUB_NAME"`
+	Books []Book
+}
+
+type Book struct {
+	db.Marker
+	Entity
+
+	Title       string
+	PublisherID *int64 `sql:"fk=Publisher"`
+	Publisher   *Publisher
+}
+
+type Review struct {
+	ID     *int64 `sql:"key"`
+	BookID *int64 `sql:"fk=Book"`
+	Stars  int
+}
+
+// Summary has no key, so it is not mapped
+type Summary struct {
+	Total int
+}
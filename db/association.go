@@ -188,6 +188,7 @@ func NewAssociation(relations ...Relation) *Association {
 			err: err,
 		}
 	}
+	a.tableFrom.addReference(a)
 	return a
 }
 
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+
+	"github.com/quintans/faults"
+	"github.com/quintans/goSQL/dbx"
+)
+
+// statements keeps the SQL of a batch, by shape, and the prepared statements, by SQL,
+// so that elements with the same shape reuse the same SQL and statement
+type statements struct {
+	db    IDb
+	sqls  map[string]*RawSql
+	stmts map[string]*sql.Stmt
+}
+
+func newStatements(db IDb) *statements {
+	return &statements{
+		db:    db,
+		sqls:  map[string]*RawSql{},
+		stmts: map[string]*sql.Stmt{},
+	}
+}
+
+func (s *statements) exec(query string, params []interface{}) (int64, error) {
+	preparer, ok := s.db.GetConnection().(dbx.IPreparer)
+	if !ok {
+		// the connection is not able to prepare statements
+		result, err := s.db.GetConnection().ExecContext(s.db.GetContext(), query, params...)
+		if err != nil {
+			return 0, faults.Wrap(err)
+		}
+		affected, err := result.RowsAffected()
+		return affected, faults.Wrap(err)
+	}
+
+	stmt, ok := s.stmts[query]
+	if !ok {
+		var err error
+		stmt, err = preparer.PrepareContext(s.db.GetContext(), query)
+		if err != nil {
+			return 0, faults.Errorf("preparing statement\nSQL: %s: %w", query, err)
+		}
+		s.stmts[query] = stmt
+	}
+
+	result, err := stmt.ExecContext(s.db.GetContext(), params...)
+	if err != nil {
+		return 0, faults.Errorf("executing statement\nSQL: %s\nParameters: %v: %w", query, params, err)
+	}
+	affected, err := result.RowsAffected()
+	return affected, faults.Wrap(err)
+}
+
+func (s *statements) close() {
+	for _, stmt := range s.stmts {
+		stmt.Close()
+	}
+}
+
+// submitAll calls submit for every element of the slice, aggregating the affected rows.
+// Optimistic lock failures do not stop the batch and are returned, by element index, in a *dbx.BatchFail.
+func submitAll(db IDb, slice interface{}, submit func(instance interface{}, stmts *statements) (int64, error)) (int64, error) {
+	arr := reflect.ValueOf(slice)
+	if arr.Kind() == reflect.Ptr {
+		arr = arr.Elem()
+	}
+	if arr.Kind() != reflect.Slice {
+		return 0, faults.Errorf("expected a slice of struct pointers. got %T", slice)
+	}
+
+	stmts := newStatements(db)
+	defer stmts.close()
+
+	fail := dbx.NewBatchFail()
+	var total int64
+	for i := 0; i < arr.Len(); i++ {
+		elem := arr.Index(i)
+		if elem.Kind() != reflect.Ptr {
+			elem = elem.Addr()
+		}
+		affected, err := submit(elem.Interface(), stmts)
+		if err != nil {
+			var lockFail *dbx.OptimisticLockFail
+			if errors.As(err, &lockFail) {
+				fail.Fails[i] = err
+				continue
+			}
+			return total, faults.Errorf("element %d: %w", i, err)
+		}
+		total += affected
+	}
+
+	if len(fail.Fails) > 0 {
+		return total, fail
+	}
+	return total, nil
+}
@@ -0,0 +1,169 @@
+package db
+
+import (
+	"database/sql/driver"
+	"reflect"
+
+	"github.com/quintans/faults"
+)
+
+// RowIterator supplies the instances to be bulk loaded
+type RowIterator interface {
+	// Next returns the next struct, or pointer to a struct, to be loaded.
+	// It returns nil when there are no more instances.
+	Next() (interface{}, error)
+}
+
+// RowIteratorFunc is a function implementing RowIterator
+type RowIteratorFunc func() (interface{}, error)
+
+func (f RowIteratorFunc) Next() (interface{}, error) {
+	return f()
+}
+
+// SliceRows returns a RowIterator over the elements of a slice of structs or pointers to structs
+func SliceRows(slice interface{}) RowIterator {
+	arr := reflect.ValueOf(slice)
+	if arr.Kind() == reflect.Ptr {
+		arr = arr.Elem()
+	}
+	if arr.Kind() != reflect.Slice {
+		return RowIteratorFunc(func() (interface{}, error) {
+			return nil, faults.Errorf("expected a slice. got %T", slice)
+		})
+	}
+
+	i := 0
+	return RowIteratorFunc(func() (interface{}, error) {
+		if i >= arr.Len() {
+			return nil, nil
+		}
+		v := arr.Index(i).Interface()
+		i++
+		return v, nil
+	})
+}
+
+// ValuesReader returns the database values of the next row, in the order of the columns being loaded.
+// It returns nil values when there are no more rows.
+type ValuesReader func() ([]interface{}, error)
+
+// BulkLoad loads the instances supplied by the iterator into the table,
+// using the native bulk path of the database, if available.
+// Columns and values are obtained from the struct mapping, including converters, as in Create.
+//
+// The loaded columns are decided by the first instance:
+// a key column is only loaded if the first instance has a value for it,
+// otherwise it is left to be generated by the database.
+// Triggers are not called and generated keys are not set back in the instances.
+// Version columns are set to 1.
+//
+// Returns the number of loaded rows.
+func (d *Db) BulkLoad(table *Table, rows RowIterator) (int64, error) {
+	first, err := rows.Next()
+	if err != nil {
+		return 0, faults.Wrap(err)
+	}
+	if first == nil {
+		return 0, nil
+	}
+
+	typ := reflect.TypeOf(first)
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return 0, faults.Errorf("expected a struct or a pointer to a struct. got %T", first)
+	}
+
+	mappings, err := d.PopulateMapping("", typ)
+	if err != nil {
+		return 0, faults.Wrap(err)
+	}
+
+	firstElem := reflect.Indirect(reflect.ValueOf(first))
+	columns := []*Column{}
+	// the properties of the columns. nil for the version column
+	properties := []*EntityProperty{}
+	for e := table.GetColumns().Enumerator(); e.HasNext(); {
+		column := e.Next().(*Column)
+		if column.IsVersion() {
+			columns = append(columns, column)
+			properties = append(properties, nil)
+			continue
+		}
+
+		bp := mappings[column.GetAlias()]
+		if bp == nil {
+			continue
+		}
+		if column.IsKey() {
+			value, err := bulkValue(bp, firstElem)
+			if err != nil {
+				return 0, faults.Wrap(err)
+			}
+			if isZero(value) {
+				continue
+			}
+		}
+		columns = append(columns, column)
+		properties = append(properties, bp)
+	}
+
+	next := first
+	reader := func() ([]interface{}, error) {
+		instance := next
+		if instance == nil {
+			var err error
+			instance, err = rows.Next()
+			if err != nil {
+				return nil, faults.Wrap(err)
+			}
+			if instance == nil {
+				return nil, nil
+			}
+		}
+		next = nil
+
+		elem := reflect.Indirect(reflect.ValueOf(instance))
+		if elem.Type() != typ {
+			return nil, faults.Errorf("all instances must be of type %s. got %T", typ, instance)
+		}
+
+		values := make([]interface{}, len(columns))
+		for k, bp := range properties {
+			if bp == nil {
+				values[k] = int64(1)
+				continue
+			}
+			value, err := bulkValue(bp, elem)
+			if err != nil {
+				return nil, faults.Wrap(err)
+			}
+			values[k] = value
+		}
+		return values, nil
+	}
+
+	markWrite(d)
+	affected, err := d.GetTranslator().BulkLoad(d, table, columns, reader)
+	return affected, faults.Wrap(err)
+}
+
+// bulkValue returns the database value of the property, as Insert does
+func bulkValue(bp *EntityProperty, elem reflect.Value) (interface{}, error) {
+	v := bp.Get(elem)
+	if !v.IsValid() || v.Kind() == reflect.Ptr && v.IsNil() {
+		return bp.ConvertToDb(nil)
+	}
+
+	val := v.Interface()
+	if valuer, ok := val.(driver.Valuer); ok {
+		var err error
+		val, err = valuer.Value()
+		if err != nil {
+			return nil, faults.Wrap(err)
+		}
+	}
+	return bp.ConvertToDb(val)
+}
@@ -0,0 +1,202 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/quintans/faults"
+)
+
+// CatalogTable is a table as described by the database catalog
+type CatalogTable struct {
+	Name        string
+	Columns     []*CatalogColumn
+	Keys        []string
+	ForeignKeys []*CatalogForeignKey
+}
+
+// CatalogColumn is a column as described by the database catalog
+type CatalogColumn struct {
+	Name string
+	// DataType is the type as named by the database
+	DataType string
+	// Type is the closest column type. Its Kind is TYPE_UNKNOWN if there is none.
+	Type     ColumnType
+	Nullable bool
+}
+
+// CatalogForeignKey is a foreign key as described by the database catalog
+type CatalogForeignKey struct {
+	Name      string
+	Columns   []string
+	TableTo   string
+	ColumnsTo []string
+}
+
+func (c *CatalogTable) Column(name string) *CatalogColumn {
+	for _, column := range c.Columns {
+		if strings.EqualFold(column.Name, name) {
+			return column
+		}
+	}
+	return nil
+}
+
+type DriftKind int
+
+const (
+	DRIFT_MISSING_TABLE DriftKind = iota + 1
+	DRIFT_MISSING_COLUMN
+	DRIFT_KEY_MISMATCH
+	DRIFT_MISSING_FOREIGN_KEY
+)
+
+// Drift is a difference between the table mappings and the database
+type Drift struct {
+	Kind   DriftKind
+	Table  string
+	Column string
+	Detail string
+}
+
+func (d Drift) String() string {
+	switch d.Kind {
+	case DRIFT_MISSING_TABLE:
+		return fmt.Sprintf("table %s is missing", d.Table)
+	case DRIFT_MISSING_COLUMN:
+		return fmt.Sprintf("column %s.%s is missing", d.Table, d.Column)
+	case DRIFT_KEY_MISMATCH:
+		return fmt.Sprintf("key of table %s does not match: %s", d.Table, d.Detail)
+	case DRIFT_MISSING_FOREIGN_KEY:
+		return fmt.Sprintf("foreign key of table %s is missing: %s", d.Table, d.Detail)
+	}
+	return fmt.Sprintf("%s: %s", d.Table, d.Detail)
+}
+
+var _ error = &SchemaFail{}
+
+// SchemaFail is returned by VerifySchema when the table mappings do not match the database
+type SchemaFail struct {
+	Drifts []Drift
+}
+
+func (s *SchemaFail) Error() string {
+	msgs := make([]string, len(s.Drifts))
+	for k, d := range s.Drifts {
+		msgs[k] = d.String()
+	}
+	return "the database does not match the mappings:\n" + strings.Join(msgs, "\n")
+}
+
+// VerifySchema compares the table mappings with the tables read from the database catalog,
+// returning a *SchemaFail with the missing tables and columns, the keys that do not match and the missing foreign keys.
+// Names are compared ignoring case. If no table is supplied, all the tables of the schema of the store are verified.
+func VerifySchema(store IDb, tables ...*Table) error {
+	if len(tables) == 0 {
+		tables = SchemaOf(store).Tables()
+	}
+
+	catalog, err := store.GetTranslator().ReadCatalog(store)
+	if err != nil {
+		return faults.Wrap(err)
+	}
+	catalogTables := map[string]*CatalogTable{}
+	for _, t := range catalog {
+		catalogTables[strings.ToUpper(t.Name)] = t
+	}
+
+	drifts := []Drift{}
+	seen := map[string]bool{}
+	add := func(drift Drift) {
+		if key := drift.String(); !seen[key] {
+			seen[key] = true
+			drifts = append(drifts, drift)
+		}
+	}
+
+	for _, table := range tables {
+		ct := catalogTables[strings.ToUpper(table.GetName())]
+		if ct == nil {
+			add(Drift{Kind: DRIFT_MISSING_TABLE, Table: table.GetName()})
+			continue
+		}
+
+		for e := table.GetColumns().Enumerator(); e.HasNext(); {
+			column := e.Next().(*Column)
+			if ct.Column(column.GetName()) == nil {
+				add(Drift{Kind: DRIFT_MISSING_COLUMN, Table: table.GetName(), Column: column.GetName()})
+			}
+		}
+
+		keys := []string{}
+		for e := table.GetKeyColumns().Enumerator(); e.HasNext(); {
+			keys = append(keys, e.Next().(*Column).GetName())
+		}
+		if mapped, actual := sortedUpper(keys), sortedUpper(ct.Keys); mapped != actual {
+			add(Drift{
+				Kind:   DRIFT_KEY_MISMATCH,
+				Table:  table.GetName(),
+				Detail: fmt.Sprintf("mapped (%s), database (%s)", mapped, actual),
+			})
+		}
+	}
+
+	for _, fk := range ForeignKeys(tables) {
+		ct := catalogTables[strings.ToUpper(fk.Table.GetName())]
+		if ct == nil || catalogTables[strings.ToUpper(fk.TableTo.GetName())] == nil {
+			// already reported
+			continue
+		}
+		if !hasForeignKey(ct, fk) {
+			from := make([]string, len(fk.Columns))
+			to := make([]string, len(fk.ColumnsTo))
+			for k := range fk.Columns {
+				from[k] = fk.Columns[k].GetName()
+				to[k] = fk.ColumnsTo[k].GetName()
+			}
+			add(Drift{
+				Kind:   DRIFT_MISSING_FOREIGN_KEY,
+				Table:  fk.Table.GetName(),
+				Detail: fmt.Sprintf("(%s) references %s (%s)", strings.Join(from, ", "), fk.TableTo.GetName(), strings.Join(to, ", ")),
+			})
+		}
+	}
+
+	if len(drifts) > 0 {
+		return &SchemaFail{Drifts: drifts}
+	}
+	return nil
+}
+
+// hasForeignKey checks if the catalog table has a foreign key with the same columns, referencing the same columns
+func hasForeignKey(table *CatalogTable, fk *ForeignKey) bool {
+	pairs := make([]string, len(fk.Columns))
+	for k := range fk.Columns {
+		pairs[k] = fk.Columns[k].GetName() + ">" + fk.ColumnsTo[k].GetName()
+	}
+	mapped := sortedUpper(pairs)
+
+	for _, cfk := range table.ForeignKeys {
+		if !strings.EqualFold(cfk.TableTo, fk.TableTo.GetName()) || len(cfk.Columns) != len(cfk.ColumnsTo) {
+			continue
+		}
+		pairs := make([]string, len(cfk.Columns))
+		for k := range cfk.Columns {
+			pairs[k] = cfk.Columns[k] + ">" + cfk.ColumnsTo[k]
+		}
+		if sortedUpper(pairs) == mapped {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedUpper(names []string) string {
+	list := make([]string, len(names))
+	for k, n := range names {
+		list[k] = strings.ToUpper(n)
+	}
+	sort.Strings(list)
+	return strings.Join(list, ", ")
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"strings"
+	"time"
 
 	tk "github.com/quintans/toolkit"
 )
@@ -16,6 +17,16 @@ type Column struct {
 	deletion  bool
 	hash      int
 
+	// values of a deletion flag. nil for a deletion timestamp
+	deleted    interface{}
+	notDeleted interface{}
+
+	// declared type. nil if not declared
+	typ          *ColumnType
+	nullable     bool
+	defaultValue interface{}
+	hasDefault   bool
+
 	err error
 }
 
@@ -70,6 +81,66 @@ func (c *Column) Deletion() *Column {
 	return c
 }
 
+// DeletionFlag sets this as a deletion column holding a flag,
+// with the value of the deleted rows and the value of the rows not deleted.
+// Without a flag, a deletion column holds the time of the deletion and is NULL for the rows not deleted.
+func (c *Column) DeletionFlag(deleted, notDeleted interface{}) *Column {
+	c.deleted = deleted
+	c.notDeleted = notDeleted
+	return c.Deletion()
+}
+
+// Type declares the database type of the column.
+// Values are validated against it before inserts and updates.
+// A typed column does not accept nil values, unless declared Nullable.
+func (c *Column) Type(typ ColumnType) *Column {
+	c.typ = &typ
+	return c
+}
+
+// Nullable declares that a typed column accepts nil values
+func (c *Column) Nullable() *Column {
+	c.nullable = true
+	return c
+}
+
+// Default declares the value of the column used by inserts when no value, or a nil value, is supplied
+func (c *Column) Default(value interface{}) *Column {
+	c.defaultValue = value
+	c.hasDefault = true
+	return c
+}
+
+// GetType returns the declared type of the column, or nil if it was not declared
+func (c *Column) GetType() *ColumnType {
+	return c.typ
+}
+
+func (c *Column) IsNullable() bool {
+	return c.nullable
+}
+
+func (c *Column) GetDefault() interface{} {
+	return c.defaultValue
+}
+
+func (c *Column) HasDefault() bool {
+	return c.hasDefault
+}
+
+// IsDeletionFlag returns true if the column is a deletion column holding a flag, instead of a timestamp
+func (c *Column) IsDeletionFlag() bool {
+	return c.deletion && c.deleted != nil
+}
+
+// deletedValue returns the value that marks a row as deleted
+func (c *Column) deletedValue() interface{} {
+	if c.deleted == nil {
+		return time.Now()
+	}
+	return c.deleted
+}
+
 //	Gets the table that this column belongs to
 //
 //	returns the table
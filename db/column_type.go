@@ -0,0 +1,328 @@
+package db
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// TypeKind is the family of a column type
+type TypeKind int
+
+const (
+	TYPE_UNKNOWN TypeKind = iota
+	TYPE_CHAR
+	TYPE_VARCHAR
+	TYPE_TEXT
+	TYPE_SMALLINT
+	TYPE_INTEGER
+	TYPE_BIGINT
+	TYPE_DECIMAL
+	TYPE_DOUBLE
+	TYPE_BOOLEAN
+	TYPE_DATE
+	TYPE_TIMESTAMP
+	TYPE_BINARY
+)
+
+var typeKindNames = map[TypeKind]string{
+	TYPE_UNKNOWN:   "UNKNOWN",
+	TYPE_CHAR:      "CHAR",
+	TYPE_VARCHAR:   "VARCHAR",
+	TYPE_TEXT:      "TEXT",
+	TYPE_SMALLINT:  "SMALLINT",
+	TYPE_INTEGER:   "INTEGER",
+	TYPE_BIGINT:    "BIGINT",
+	TYPE_DECIMAL:   "DECIMAL",
+	TYPE_DOUBLE:    "DOUBLE",
+	TYPE_BOOLEAN:   "BOOLEAN",
+	TYPE_DATE:      "DATE",
+	TYPE_TIMESTAMP: "TIMESTAMP",
+	TYPE_BINARY:    "BINARY",
+}
+
+func (k TypeKind) String() string {
+	return typeKindNames[k]
+}
+
+// ColumnType is the declared database type of a column
+type ColumnType struct {
+	Kind TypeKind
+	// maximum number of characters of CHAR and VARCHAR
+	Length int
+	// total and fractional digits of DECIMAL
+	Precision int
+	Scale     int
+}
+
+func (t ColumnType) String() string {
+	switch t.Kind {
+	case TYPE_CHAR, TYPE_VARCHAR:
+		return fmt.Sprintf("%s(%d)", t.Kind, t.Length)
+	case TYPE_DECIMAL:
+		return fmt.Sprintf("%s(%d,%d)", t.Kind, t.Precision, t.Scale)
+	default:
+		return t.Kind.String()
+	}
+}
+
+func Char(length int) ColumnType {
+	return ColumnType{Kind: TYPE_CHAR, Length: length}
+}
+
+func Varchar(length int) ColumnType {
+	return ColumnType{Kind: TYPE_VARCHAR, Length: length}
+}
+
+// Text is a character column without a declared length, like CLOB or TEXT
+func Text() ColumnType {
+	return ColumnType{Kind: TYPE_TEXT}
+}
+
+func Smallint() ColumnType {
+	return ColumnType{Kind: TYPE_SMALLINT}
+}
+
+func Integer() ColumnType {
+	return ColumnType{Kind: TYPE_INTEGER}
+}
+
+func Bigint() ColumnType {
+	return ColumnType{Kind: TYPE_BIGINT}
+}
+
+func Decimal(precision, scale int) ColumnType {
+	return ColumnType{Kind: TYPE_DECIMAL, Precision: precision, Scale: scale}
+}
+
+func Double() ColumnType {
+	return ColumnType{Kind: TYPE_DOUBLE}
+}
+
+func Boolean() ColumnType {
+	return ColumnType{Kind: TYPE_BOOLEAN}
+}
+
+func Date() ColumnType {
+	return ColumnType{Kind: TYPE_DATE}
+}
+
+func Timestamp() ColumnType {
+	return ColumnType{Kind: TYPE_TIMESTAMP}
+}
+
+// Binary is a column of bytes, like BLOB or BYTEA
+func Binary() ColumnType {
+	return ColumnType{Kind: TYPE_BINARY}
+}
+
+// check returns the reason why the value, not nil, does not fit the type, or an empty string if it fits
+func (t ColumnType) check(value interface{}) string {
+	v := reflect.ValueOf(value)
+	switch t.Kind {
+	case TYPE_CHAR, TYPE_VARCHAR:
+		if v.Kind() == reflect.String && t.Length > 0 {
+			if n := utf8.RuneCountInString(v.String()); n > t.Length {
+				return fmt.Sprintf("length %d exceeds %s", n, t)
+			}
+		}
+	case TYPE_SMALLINT:
+		return checkRange(v, math.MinInt16, math.MaxInt16, t)
+	case TYPE_INTEGER:
+		return checkRange(v, math.MinInt32, math.MaxInt32, t)
+	case TYPE_BIGINT:
+		return checkRange(v, math.MinInt64, math.MaxInt64, t)
+	case TYPE_DECIMAL:
+		if t.Precision > 0 {
+			limit := math.Pow10(t.Precision - t.Scale)
+			if f, ok := toFloat(v); ok && math.Abs(f) >= limit {
+				return fmt.Sprintf("%v is out of range for %s", value, t)
+			}
+		}
+	}
+	return ""
+}
+
+func checkRange(v reflect.Value, min, max float64, t ColumnType) string {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > uint64(max) {
+			return fmt.Sprintf("%v is out of range for %s", v.Interface(), t)
+		}
+		return ""
+	}
+	f, ok := toFloat(v)
+	if !ok {
+		return ""
+	}
+	if f < min || f > max {
+		return fmt.Sprintf("%v is out of range for %s", v.Interface(), t)
+	}
+	if f != math.Trunc(f) {
+		return fmt.Sprintf("%v is not an integer", v.Interface())
+	}
+	return ""
+}
+
+func toFloat(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+var _ error = &TypeFail{}
+
+// TypeFail is returned, before executing an insert or an update,
+// when values do not comply with the declared types of the columns
+type TypeFail struct {
+	Table      string
+	Violations []TypeViolation
+}
+
+// TypeViolation is a value that does not comply with the declared type of a column
+type TypeViolation struct {
+	Column string
+	Value  interface{}
+	Reason string
+}
+
+func (t *TypeFail) Error() string {
+	msgs := make([]string, len(t.Violations))
+	for k, v := range t.Violations {
+		msgs[k] = v.Column + ": " + v.Reason
+	}
+	return fmt.Sprintf("invalid values for %s: %s", t.Table, strings.Join(msgs, "; "))
+}
+
+// validate verifies the values being set, before an insert or an update is executed.
+// With insert, nil or missing values of columns with a default are replaced by the default.
+// The values of typed columns are then replaced by the values to bind, given by the translator.
+func (d *DmlCore) validate(insert bool) error {
+	if insert {
+		d.applyDefaults()
+	}
+	if err := d.checkMandatory(insert); err != nil {
+		return err
+	}
+
+	translator := d.db.GetTranslator()
+	var violations []TypeViolation
+	for e := d.table.GetColumns().Enumerator(); e.HasNext(); {
+		column := e.Next().(*Column)
+		typ := column.GetType()
+		if typ == nil || d.vals == nil {
+			continue
+		}
+		v, ok := d.vals.Get(column)
+		if !ok {
+			continue
+		}
+		token := v.(Tokener)
+
+		if d.isNilToken(token) {
+			if !column.IsNullable() && !column.IsKey() && !column.IsVersion() {
+				violations = append(violations, TypeViolation{
+					Column: column.GetName(),
+					Reason: "cannot be null",
+				})
+			}
+			continue
+		}
+		if token.GetOperator() != TOKEN_PARAM {
+			continue
+		}
+
+		name := token.GetValue().(string)
+		value, ok := d.parameters[name]
+		if !ok {
+			continue
+		}
+		value, err := driverValue(value)
+		if err != nil {
+			// it will be reported when executing
+			continue
+		}
+		if v := reflect.ValueOf(value); v.Kind() == reflect.Ptr {
+			value = v.Elem().Interface()
+		}
+		if reason := typ.check(value); reason != "" {
+			violations = append(violations, TypeViolation{
+				Column: column.GetName(),
+				Value:  value,
+				Reason: reason,
+			})
+			continue
+		}
+		bound, err := translator.BindValue(column, value)
+		if err != nil {
+			return err
+		}
+		d.parameters[name] = bound
+	}
+
+	if len(violations) > 0 {
+		sort.SliceStable(violations, func(i, j int) bool {
+			return violations[i].Column < violations[j].Column
+		})
+		return &TypeFail{
+			Table:      d.table.GetName(),
+			Violations: violations,
+		}
+	}
+	return nil
+}
+
+// validStatement validates the values being set, as before an execution, and returns the statement of the SQL.
+// The bound values are only kept in the statement, so that they are not bound twice if the DML is executed.
+func (d *DmlCore) validStatement(insert bool, rsql func() *RawSql) (Statement, error) {
+	parameters := make(map[string]interface{}, len(d.parameters))
+	for k, v := range d.parameters {
+		parameters[k] = v
+	}
+	defer func() {
+		d.parameters = parameters
+	}()
+
+	if err := d.validate(insert); err != nil {
+		return Statement{}, err
+	}
+	return d.toStatement(rsql())
+}
+
+// applyDefaults sets the default of the columns that are missing or are nil
+func (d *DmlCore) applyDefaults() {
+	for e := d.table.GetColumns().Enumerator(); e.HasNext(); {
+		column := e.Next().(*Column)
+		if !column.HasDefault() || column.IsKey() || column.IsVersion() {
+			continue
+		}
+		if d.vals != nil {
+			if v, ok := d.vals.Get(column); ok && !d.isNilToken(v.(Tokener)) {
+				continue
+			}
+		}
+		d.set(column, column.GetDefault())
+	}
+}
+
+// driverValue returns the value of a driver.Valuer, or the value itself
+func driverValue(value interface{}) (interface{}, error) {
+	valuer, ok := value.(driver.Valuer)
+	if !ok {
+		return value, nil
+	}
+	if v := reflect.ValueOf(valuer); v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil, nil
+	}
+	return valuer.Value()
+}
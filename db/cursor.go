@@ -0,0 +1,152 @@
+package db
+
+import (
+	"database/sql"
+	"reflect"
+
+	"github.com/quintans/faults"
+)
+
+// Cursor iterates over the results of a query, one row at a time,
+// without holding the whole result in memory.
+//
+// ex:
+//   cursor, err := store.Query(BOOK).All().Iterate()
+//   if err != nil {
+//     return err
+//   }
+//   defer cursor.Close()
+//   for cursor.Next() {
+//     var book Book
+//     if err := cursor.Scan(&book); err != nil {
+//       return err
+//     }
+//     ...
+//   }
+//   return cursor.Err()
+type Cursor struct {
+	query *Query
+	rows  *sql.Rows
+	// the transformers are reused between rows, one per target type
+	transformers map[reflect.Type]*EntityTransformer
+	current      reflect.Value
+	err          error
+}
+
+// Iterate executes the query and returns a cursor over the results.
+// The cursor must be closed after use.
+func (q *Query) Iterate() (*Cursor, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+
+	// if no columns were added, add all columns of the driving table
+	if len(q.Columns) == 0 {
+		q.All()
+	}
+
+	rsql, err := q.getCachedSql()
+	if err != nil {
+		return nil, err
+	}
+	q.debugSQL(rsql.OriSql, 1)
+
+	params, err := rsql.BuildValues(q.DmlBase.parameters)
+	if err != nil {
+		return nil, faults.Wrap(err)
+	}
+	rows, err := q.queryDba().QueryRowsX(q.db.GetContext(), rsql.Sql, params...)
+	if err != nil {
+		return nil, faults.Wrap(err)
+	}
+
+	return &Cursor{
+		query:        q,
+		rows:         rows,
+		transformers: map[reflect.Type]*EntityTransformer{},
+	}, nil
+}
+
+// Next prepares the next row to be read with Scan.
+// It returns false when there are no more rows or if an error happened.
+func (c *Cursor) Next() bool {
+	if c.err != nil {
+		return false
+	}
+	return c.rows.Next()
+}
+
+// Scan copies the current row into dest.
+//
+// dest can be a pointer to a struct, a pointer to a pointer to a struct,
+// where a new struct is created, or anything accepted by sql.Rows.Scan.
+// With an identity map, a pointer to a pointer gets the instance already loaded in the transaction,
+// and a pointer to a struct gets a copy of it.
+func (c *Cursor) Scan(dest interface{}) error {
+	if c.err != nil {
+		return c.err
+	}
+
+	val := reflect.ValueOf(dest)
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		return faults.Errorf("expected a pointer. got %T", dest)
+	}
+
+	elem := val.Elem()
+	var target reflect.Value
+	if elem.Kind() == reflect.Struct && !isScanner(val.Type()) {
+		// dest is reused between rows, so it cannot be the instance kept in the identity map
+		target = reflect.New(elem.Type())
+	} else if elem.Kind() == reflect.Ptr && elem.Type().Elem().Kind() == reflect.Struct && !isScanner(elem.Type()) {
+		target = reflect.New(elem.Type().Elem())
+	} else {
+		if err := c.rows.Scan(dest); err != nil {
+			c.err = faults.Wrap(err)
+			return c.err
+		}
+		return nil
+	}
+
+	transformer, ok := c.transformers[target.Type()]
+	if !ok {
+		transformer = NewEntityFactoryTransformer(c.query, target.Type(), nil)
+		transformer.Factory = func() reflect.Value {
+			return c.current
+		}
+		c.transformers[target.Type()] = transformer
+	}
+	c.current = target
+	instance, err := transformer.Transform(c.rows)
+	if err != nil {
+		c.err = faults.Wrap(err)
+		return c.err
+	}
+
+	// the identity map may resolve to an instance loaded before
+	resolved := reflect.ValueOf(instance)
+	if elem.Kind() == reflect.Struct {
+		elem.Set(resolved.Elem())
+	} else {
+		elem.Set(resolved)
+	}
+	return nil
+}
+
+// Err returns the error, if any, that happened during the iteration
+func (c *Cursor) Err() error {
+	if c.err != nil {
+		return c.err
+	}
+	return faults.Wrap(c.rows.Err())
+}
+
+// Close closes the cursor. It is safe to call it more than once.
+func (c *Cursor) Close() error {
+	return faults.Wrap(c.rows.Close())
+}
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+func isScanner(typ reflect.Type) bool {
+	return typ.Implements(scannerType)
+}
@@ -0,0 +1,42 @@
+//go:build go1.23
+
+package db
+
+import "iter"
+
+// Iterate executes the query and returns an iterator over the results,
+// where T is a struct, a pointer to a struct or a type accepted by sql.Rows.Scan.
+// If an error happens, it is the last value returned by the iterator.
+//
+// ex:
+//   for book, err := range db.Iterate[*Book](store.Query(BOOK).All()) {
+//     if err != nil {
+//       return err
+//     }
+//     ...
+//   }
+func Iterate[T any](query *Query) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		cursor, err := query.Iterate()
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer cursor.Close()
+
+		for cursor.Next() {
+			var value T
+			if err := cursor.Scan(&value); err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(value, nil) {
+				return
+			}
+		}
+		if err := cursor.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
@@ -6,7 +6,6 @@ import (
 
 	"github.com/quintans/faults"
 	"github.com/quintans/goSQL/dbx"
-	"github.com/quintans/toolkit/ext"
 	"github.com/quintans/toolkit/log"
 )
 
@@ -22,6 +21,11 @@ const (
 	sqlOmissionVal = "omit"
 	sqlEmbededVal  = "embeded" // fixing the typo will be a breaking change
 	sqlEmbeddedVal = "embedded"
+	sqlKeyVal      = "key"
+	sqlVersionVal  = "version"
+	columnTag      = "column="
+	colTag         = "col="
+	fkTag          = "fk="
 )
 
 // Interface that a struct must implement to inform what columns where changed
@@ -82,6 +86,13 @@ type IDb interface {
 	Remove(instance interface{}) (bool, error)
 	RemoveAll(instance interface{}) (int64, error)
 	Save(instance interface{}) (bool, error) // Create or Modify
+	BulkLoad(table *Table, rows RowIterator) (int64, error)
+
+	Savepoint(name string) error
+	RollbackTo(name string) error
+	Release(name string) error
+	OnCommit(hook func())
+	OnRollback(hook func())
 
 	GetAttribute(string) (interface{}, bool)
 	SetAttribute(string, interface{}) // general attribute. ex: user in session
@@ -127,6 +138,11 @@ func (d *Db) GetConnection() dbx.IConnection {
 	return d.Connection
 }
 
+// Schema returns the schema of the transaction manager that created the store
+func (d *Db) Schema() *Schema {
+	return SchemaOf(d.mapper)
+}
+
 // the idea is to centralize the query creation so that future customization could be made
 func (d *Db) Query(table *Table) *Query {
 	return NewQuery(d, table)
@@ -147,8 +163,8 @@ func (d *Db) Update(table *Table) *Update {
 	return NewUpdate(d, table)
 }
 
-// finds the registered table for the passed struct
-func structName(instance interface{}) (*Table, reflect.Type, error) {
+// finds the table registered in the schema for the passed struct
+func structName(schema *Schema, instance interface{}) (*Table, reflect.Type, error) {
 	typ := reflect.TypeOf(instance)
 	// slice
 	if typ.Kind() == reflect.Slice {
@@ -161,30 +177,29 @@ func structName(instance interface{}) (*Table, reflect.Type, error) {
 		typ = typ.Elem()
 	}
 
-	var tab interface{}
-	var ok bool
+	var tab *Table
 	if t, isT := instance.(TableNamer); isT {
-		tab, ok = Tables.Get(ext.Str(t.TableName()))
-		if !ok {
+		tab = schema.Table(t.TableName())
+		if tab == nil {
 			return nil, nil, faults.Errorf("There is no table mapped to TableName %s", t.TableName())
 		}
 	} else {
-		tab, ok = Tables.Get(ext.Str(typ.Name()))
-		if !ok {
+		tab = schema.Table(typ.Name())
+		if tab == nil {
 			// tries to find using also the struct package.
 			// The package corresponds to the database schema
-			tab, ok = Tables.Get(ext.Str(typ.PkgPath() + "." + typ.Name()))
-			if !ok {
+			tab = schema.Table(typ.PkgPath() + "." + typ.Name())
+			if tab == nil {
 				return nil, nil, faults.Errorf("There is no table mapped to Struct Type %s", typ.Name())
 			}
 		}
 	}
 
-	return tab.(*Table), typ, nil
+	return tab, typ, nil
 }
 
 func (d *Db) Create(instance interface{}) error {
-	table, _, err := structName(instance)
+	table, _, err := structName(d.Schema(), instance)
 	if err != nil {
 		return faults.Wrap(err)
 	}
@@ -192,7 +207,10 @@ func (d *Db) Create(instance interface{}) error {
 	dml := d.Insert(table)
 
 	_, err = dml.Submit(instance)
-	return faults.Wrap(err)
+	if err != nil {
+		return faults.Wrap(err)
+	}
+	return faults.Wrap(d.syncIdentity(table, instance, false))
 }
 
 // struct field with `sql:"omit"` should be ignored if value is zero in an update.
@@ -216,11 +234,18 @@ func (d *Db) acceptColumn(table *Table, t reflect.Type, handler func(*Column)) e
 }
 
 func (d *Db) Retrieve(instance interface{}, keys ...interface{}) (bool, error) {
-	table, t, err := structName(instance)
+	table, t, err := structName(d.Schema(), instance)
 	if err != nil {
 		return false, faults.Wrap(err)
 	}
 
+	if identities := identityMapOf(d); identities != nil && len(keys) == table.GetKeyColumns().Size() {
+		if cached, ok := identities.get(table, t, keys); ok {
+			reflect.ValueOf(instance).Elem().Set(cached.Elem())
+			return true, nil
+		}
+	}
+
 	dml := d.Query(table)
 	if err := d.acceptColumn(table, t, func(c *Column) {
 		dml.Column(c)
@@ -307,7 +332,7 @@ func (d *Db) buildCriteria(table *Table, example interface{}) ([]*Criteria, erro
 }
 
 func (d *Db) find(instance interface{}, example interface{}) (*Query, error) {
-	table, t, err := structName(instance)
+	table, t, err := structName(d.Schema(), instance)
 	if err != nil {
 		return nil, faults.Wrap(err)
 	}
@@ -348,20 +373,29 @@ func (d *Db) FindAll(instance interface{}, example interface{}) error {
 }
 
 func (d *Db) Modify(instance interface{}) (bool, error) {
-	table, _, err := structName(instance)
+	table, _, err := structName(d.Schema(), instance)
 	if err != nil {
 		return false, faults.Wrap(err)
 	}
 
 	dml := d.Update(table)
 
+	// only some columns are updated
+	partial := false
+	if markable, ok := instance.(Markable); ok {
+		partial = len(markable.Marks()) > 0
+	}
+
 	var key int64
 	key, err = dml.Submit(instance)
-	return key != 0, faults.Wrap(err)
+	if err != nil {
+		return false, faults.Wrap(err)
+	}
+	return key != 0, faults.Wrap(d.syncIdentity(table, instance, partial))
 }
 
 func (d *Db) Remove(instance interface{}) (bool, error) {
-	table, _, err := structName(instance)
+	table, _, err := structName(d.Schema(), instance)
 	if err != nil {
 		return false, faults.Wrap(err)
 	}
@@ -370,12 +404,35 @@ func (d *Db) Remove(instance interface{}) (bool, error) {
 
 	var deleted int64
 	deleted, err = dml.Submit(instance)
-	return deleted != 0, faults.Wrap(err)
+	if err != nil {
+		return false, faults.Wrap(err)
+	}
+	return deleted != 0, faults.Wrap(d.syncIdentity(table, instance, true))
+}
+
+// syncIdentity keeps the identity map of the transaction in sync with a created, modified or removed instance.
+// With remove, the instance is taken out of the map.
+func (d *Db) syncIdentity(table *Table, instance interface{}, remove bool) error {
+	identities := identityMapOf(d)
+	if identities == nil {
+		return nil
+	}
+	val := reflect.ValueOf(instance)
+	mappings, err := d.PopulateMapping("", val.Type())
+	if err != nil {
+		return faults.Wrap(err)
+	}
+	if remove {
+		identities.remove(table, mappings, val)
+	} else {
+		identities.sync(table, mappings, val)
+	}
+	return nil
 }
 
 // removes all that match the criteria defined by the non zero values by the struct.
 func (d *Db) RemoveAll(instance interface{}) (int64, error) {
-	table, _, err := structName(instance)
+	table, _, err := structName(d.Schema(), instance)
 	if err != nil {
 		return 0, faults.Wrap(err)
 	}
@@ -399,7 +456,7 @@ func (d *Db) RemoveAll(instance interface{}) (int64, error) {
 //If version is nil or zero, an insert is issue, otherwise an update.
 //If there is no version column it returns an error.
 func (d *Db) Save(instance interface{}) (bool, error) {
-	table, _, err := structName(instance)
+	table, _, err := structName(d.Schema(), instance)
 	if err != nil {
 		return false, faults.Wrap(err)
 	}
@@ -0,0 +1,206 @@
+package db
+
+import (
+	"strings"
+
+	"github.com/quintans/faults"
+	tk "github.com/quintans/toolkit"
+)
+
+// Index is an index, or a unique constraint, over columns of a table
+type Index struct {
+	Name    string
+	Columns []*Column
+	Unique  bool
+}
+
+// INDEX declares an index over columns of the table, used when generating DDL
+func (t *Table) INDEX(name string, columns ...*Column) *Table {
+	t.indexes = append(t.indexes, &Index{Name: name, Columns: columns})
+	return t
+}
+
+// UNIQUE declares a unique constraint over columns of the table, used when generating DDL
+func (t *Table) UNIQUE(name string, columns ...*Column) *Table {
+	t.indexes = append(t.indexes, &Index{Name: name, Columns: columns, Unique: true})
+	return t
+}
+
+func (t *Table) GetIndexes() []*Index {
+	return t.indexes
+}
+
+// addReference keeps the association, if no other with the same relations was kept
+func (t *Table) addReference(association *Association) {
+	key := tk.NewStrBuffer()
+	for _, r := range association.GetRelations() {
+		key.Add(r.From.GetColumn().String(), "=", r.To.GetColumn().String(), ";")
+	}
+	if t.referenceKeys == nil {
+		t.referenceKeys = map[string]bool{}
+	}
+	if !t.referenceKeys[key.String()] {
+		t.referenceKeys[key.String()] = true
+		t.references = append(t.references, association)
+	}
+}
+
+// ReferencedColumn returns the key column referenced by the column, according to the declared associations, or nil if there is none
+func ReferencedColumn(column *Column) *Column {
+	for _, a := range column.GetTable().references {
+		for _, r := range a.GetRelations() {
+			if r.From.GetColumn() == column && r.To.GetColumn().IsKey() {
+				return r.To.GetColumn()
+			}
+		}
+	}
+	// associations declared in the other direction
+	for _, table := range column.GetTable().Schema().values() {
+		for _, a := range table.references {
+			for _, r := range a.GetRelations() {
+				if r.To.GetColumn() == column && r.From.GetColumn().IsKey() {
+					return r.From.GetColumn()
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ForeignKey is a foreign key constraint derived from the associations between tables
+type ForeignKey struct {
+	Name      string
+	Table     *Table
+	Columns   []*Column
+	TableTo   *Table
+	ColumnsTo []*Column
+}
+
+// RegisteredTables returns the tables registered in the default schema, sorted by name.
+// When several logical tables are mapped to the same physical table, only the first is returned.
+func RegisteredTables() []*Table {
+	return DefaultSchema.Tables()
+}
+
+// ForeignKeys returns the foreign keys between the tables, derived from their associations.
+//
+// The side of the association whose columns are the key of its table is the referenced side.
+// Associations where both sides are keys, like one-to-one associations sharing the key, are ambiguous and are ignored,
+// as are the columns referencing more than one table, like the ones of associations with discriminators.
+func ForeignKeys(tables []*Table) []*ForeignKey {
+	inTables := map[string]*Table{}
+	for _, table := range tables {
+		inTables[strings.ToUpper(table.GetName())] = table
+	}
+
+	// referenced table, by foreign key name
+	targets := map[string]string{}
+	polymorphic := map[string]bool{}
+	fks := []*ForeignKey{}
+	add := func(association *Association) {
+		fk := foreignKeyOf(association)
+		if fk == nil || inTables[strings.ToUpper(fk.Table.GetName())] == nil || inTables[strings.ToUpper(fk.TableTo.GetName())] == nil {
+			return
+		}
+		target := strings.ToUpper(fk.TableTo.GetName())
+		if t, ok := targets[fk.Name]; !ok {
+			targets[fk.Name] = target
+			fks = append(fks, fk)
+		} else if t != target {
+			polymorphic[fk.Name] = true
+		}
+	}
+	for _, table := range tables {
+		for _, association := range table.references {
+			add(association)
+		}
+		for _, association := range table.GetAssociations() {
+			if association.IsMany2Many() {
+				add(association.FromM2M)
+				add(association.ToM2M)
+			} else {
+				add(association)
+			}
+		}
+	}
+
+	result := []*ForeignKey{}
+	for _, fk := range fks {
+		if !polymorphic[fk.Name] {
+			result = append(result, fk)
+		}
+	}
+	return result
+}
+
+func foreignKeyOf(association *Association) *ForeignKey {
+	relations := association.GetRelations()
+	if len(relations) == 0 {
+		return nil
+	}
+	from := make([]*Column, len(relations))
+	to := make([]*Column, len(relations))
+	for k, r := range relations {
+		from[k] = r.From.GetColumn()
+		to[k] = r.To.GetColumn()
+	}
+
+	fromKey := isTableKey(association.GetTableFrom(), from)
+	toKey := isTableKey(association.GetTableTo(), to)
+	switch {
+	case toKey && !fromKey:
+		return newForeignKey(association.GetTableFrom(), from, association.GetTableTo(), to)
+	case fromKey && !toKey:
+		return newForeignKey(association.GetTableTo(), to, association.GetTableFrom(), from)
+	default:
+		return nil
+	}
+}
+
+func newForeignKey(table *Table, columns []*Column, tableTo *Table, columnsTo []*Column) *ForeignKey {
+	names := make([]string, len(columns))
+	for k, c := range columns {
+		names[k] = c.GetName()
+	}
+	return &ForeignKey{
+		Name:      strings.ToUpper("FK_" + table.GetName() + "_" + strings.Join(names, "_")),
+		Table:     table,
+		Columns:   columns,
+		TableTo:   tableTo,
+		ColumnsTo: columnsTo,
+	}
+}
+
+// isTableKey returns true if the columns are exactly the key columns of the table
+func isTableKey(table *Table, columns []*Column) bool {
+	keys := table.GetKeyColumns()
+	if keys.Size() == 0 || keys.Size() != len(columns) {
+		return false
+	}
+	for _, c := range columns {
+		if !c.IsKey() || !c.GetTable().Equals(table) {
+			return false
+		}
+	}
+	return true
+}
+
+// CreateTables executes the DDL, given by the translator of the store, that creates the tables
+func CreateTables(store IDb, tables ...*Table) error {
+	return execDDL(store, store.GetTranslator().GetSqlForCreate(tables))
+}
+
+// DropTables executes the DDL, given by the translator of the store, that drops the tables
+func DropTables(store IDb, tables ...*Table) error {
+	return execDDL(store, store.GetTranslator().GetSqlForDrop(tables))
+}
+
+func execDDL(store IDb, stmts []string) error {
+	for _, sql := range stmts {
+		logger.Debugf("SQL: %s", sql)
+		if _, err := store.GetConnection().ExecContext(store.GetContext(), sql); err != nil {
+			return faults.Errorf("executing statement\nSQL: %s: %w", sql, err)
+		}
+	}
+	return nil
+}
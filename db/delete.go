@@ -18,6 +18,8 @@ type PostDeleter interface {
 
 type Delete struct {
 	DmlCore
+
+	hardDelete bool
 }
 
 func NewDelete(db IDb, table *Table) *Delete {
@@ -31,9 +33,51 @@ func (d *Delete) Alias(alias string) *Delete {
 	return d
 }
 
+// HardDelete removes the rows from a table with a deletion column,
+// instead of marking them as deleted.
+func (d *Delete) HardDelete() *Delete {
+	d.hardDelete = true
+	d.rawSQL = nil
+	return d
+}
+
+// softDeletion returns the deletion column used to mark the rows as deleted,
+// or nil if the rows are to be removed
+func (d *Delete) softDeletion() *Column {
+	if d.hardDelete {
+		return nil
+	}
+	return d.table.GetDeletionColumn()
+}
+
+// setDeletedValue sets the value of the deletion column parameter, when deleting logically
+func (d *Delete) setDeletedValue() {
+	if deletion := d.softDeletion(); deletion != nil {
+		d.SetParameter(deletion.GetAlias(), deletion.deletedValue())
+	}
+}
+
 func (d *Delete) Submit(value interface{}) (int64, error) {
+	return d.submit(value, nil)
+}
+
+// SubmitAll deletes every struct of the slice, like Submit, preparing the statement only once.
+// Returns the total number of affected rows.
+//
+// An optimistic lock failure does not stop the remaining deletes
+// and is reported, by the element index, in a *dbx.BatchFail.
+func (d *Delete) SubmitAll(slice interface{}) (int64, error) {
+	kept := d.keepValues()
+	return submitAll(d.GetDb(), slice, func(value interface{}, stmts *statements) (int64, error) {
+		d.restoreValues(kept)
+		return d.submit(value, stmts)
+	})
+}
+
+func (d *Delete) submit(value interface{}, stmts *statements) (int64, error) {
 	var mappings map[string]*EntityProperty
-	var criterias []*Criteria
+	// the criteria are built for every struct, since the version may be checked for some and not for others
+	criterias := make([]*Criteria, 0)
 
 	typ := reflect.TypeOf(value)
 	if typ.Kind() == reflect.Ptr {
@@ -48,8 +92,6 @@ func (d *Delete) Submit(value interface{}) (int64, error) {
 		if err != nil {
 			return 0, faults.Wrap(err)
 		}
-		criterias = make([]*Criteria, 0)
-		d.criteria = nil
 		d.lastMappings = mappings
 		d.lastType = typ
 	}
@@ -77,9 +119,7 @@ func (d *Delete) Submit(value interface{}) (int64, error) {
 				}
 				id := val.Interface()
 
-				if criterias != nil {
-					criterias = append(criterias, column.Matches(Param(alias)))
-				}
+				criterias = append(criterias, column.Matches(Param(alias)))
 				d.SetParameter(alias, id)
 				hasId = true
 			} else if column.IsVersion() {
@@ -93,9 +133,7 @@ func (d *Delete) Submit(value interface{}) (int64, error) {
 
 				ver = val.Int()
 				if ver != 0 {
-					if criterias != nil {
-						criterias = append(criterias, column.Matches(Param(alias)))
-					}
+					criterias = append(criterias, column.Matches(Param(alias)))
 					d.SetParameter(alias, ver)
 					mustSucceed = true
 				}
@@ -107,10 +145,7 @@ func (d *Delete) Submit(value interface{}) (int64, error) {
 		return 0, faults.Errorf("goSQL: No key field was identified in %s.", typ.String())
 	}
 
-	if criterias != nil {
-		d.Where(criterias...)
-		d.rawSQL = nil
-	}
+	d.whereKeys(criterias, d.shape(typ, mustSucceed), stmts)
 
 	// pre trigger
 	if t, isT := value.(PreDeleter); isT {
@@ -120,7 +155,7 @@ func (d *Delete) Submit(value interface{}) (int64, error) {
 		}
 	}
 
-	affectedRows, err := d.Execute()
+	affectedRows, err := d.execute(stmts)
 	if err != nil {
 		return 0, faults.Wrap(err)
 	}
@@ -136,18 +171,34 @@ func (d *Delete) Submit(value interface{}) (int64, error) {
 }
 
 func (d *Delete) Execute() (int64, error) {
+	if identities := identityMapOf(d.db); identities != nil {
+		// it is not possible to know which entities were deleted
+		identities.clear()
+	}
+	return d.execute(nil)
+}
+
+func (d *Delete) execute(stmts *statements) (int64, error) {
 	table := d.GetTable()
 	if table.PreDeleteTrigger != nil {
 		table.PreDeleteTrigger(d)
 	}
 
 	rsql := d.getCachedSql()
-	d.debugSQL(rsql.OriSql, 1)
+	d.debugSQL(rsql.OriSql, 2)
+	if stmts != nil {
+		stmts.sqls[d.lastShape] = rsql
+	}
 
+	d.setDeletedValue()
 	params, err := rsql.BuildValues(d.DmlBase.parameters)
 	if err != nil {
 		return 0, faults.Wrap(err)
 	}
+	markWrite(d.db)
+	if stmts != nil {
+		return stmts.exec(rsql.Sql, params)
+	}
 	affectedRows, e := d.DmlBase.dba.DeleteX(d.db.GetContext(), rsql.Sql, params...)
 	if e != nil {
 		return 0, e
@@ -156,6 +207,16 @@ func (d *Delete) Execute() (int64, error) {
 	return affectedRows, nil
 }
 
+// ToSQL returns the SQL, and its arguments, that would be executed by the delete, without executing it.
+// The values being set are validated as in an update.
+func (d *Delete) ToSQL() (Statement, error) {
+	return d.validStatement(false, func() *RawSql {
+		rsql := d.getCachedSql()
+		d.setDeletedValue()
+		return rsql
+	})
+}
+
 func (d *Delete) getCachedSql() *RawSql {
 	if d.rawSQL == nil {
 		// if the discriminator conditions have not yet been processed, apply them now
@@ -163,13 +224,38 @@ func (d *Delete) getCachedSql() *RawSql {
 			d.DmlBase.where(nil)
 		}
 
-		sql := d.db.GetTranslator().GetSqlForDelete(d)
+		var sql string
+		if deletion := d.softDeletion(); deletion != nil {
+			sql = d.getSqlForSoftDelete(deletion)
+		} else {
+			sql = d.db.GetTranslator().GetSqlForDelete(d)
+		}
 		d.rawSQL = ToRawSql(sql, d.db.GetTranslator())
 	}
 
 	return d.rawSQL
 }
 
+// getSqlForSoftDelete returns the UPDATE that marks, as deleted, the rows of the delete that are not yet deleted
+func (d *Delete) getSqlForSoftDelete(deletion *Column) string {
+	update := NewUpdate(d.db, d.table)
+	update.alias(d.tableAlias)
+	// shares the parameters, so that the delete values are used
+	update.parameters = d.parameters
+	update.rawIndex = d.rawIndex
+	update.Set(deletion, Param(deletion.GetAlias()))
+
+	notDeleted := d.table.notDeletedCriteria()
+	if d.criteria != nil {
+		update.applyWhere(And(d.criteria, notDeleted))
+	} else {
+		update.applyWhere(notDeleted)
+	}
+	d.rawIndex = update.rawIndex
+
+	return d.db.GetTranslator().GetSqlForUpdate(update)
+}
+
 //// WHERE ===
 
 func (d *Delete) Where(restriction ...*Criteria) *Delete {
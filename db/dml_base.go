@@ -56,6 +56,28 @@ func (r *RawSql) Clone() interface{} {
 	return other
 }
 
+// Statement is the SQL of a DML, as it would be executed
+type Statement struct {
+	// the SQL with the database specific placeholders
+	Sql string
+	// the values for the placeholders, in order
+	Args []interface{}
+	// the original SQL, with named parameters
+	OriSql string
+}
+
+func (d *DmlBase) toStatement(rsql *RawSql) (Statement, error) {
+	args, err := rsql.BuildValues(d.parameters)
+	if err != nil {
+		return Statement{}, faults.Wrap(err)
+	}
+	return Statement{
+		Sql:    rsql.Sql,
+		Args:   args,
+		OriSql: rsql.OriSql,
+	}, nil
+}
+
 type PathCriteria struct {
 	Criterias []*Criteria
 	Columns   []Tokener
@@ -81,6 +103,8 @@ type DmlBase struct {
 	cachedAssociation [][]*PathElement
 	// list with the associations of the current path
 	path []*PathElement
+	// the logically deleted rows of the joined tables are not filtered out
+	includeDeleted bool
 
 	rawSQL *RawSql
 	dba    *dbx.SimpleDBA
@@ -253,6 +277,11 @@ func (d *DmlBase) buildPathCriterias(paths []*PathElement) []*PathCriteria {
 
 		// table discriminator on target
 		tableCriterias = pe.Base.GetTableTo().GetCriterias()
+		if !d.includeDeleted {
+			if notDeleted := pe.Base.GetTableTo().notDeletedCriteria(); notDeleted != nil {
+				tableCriterias = append(tableCriterias, notDeleted)
+			}
+		}
 		if tableCriterias != nil {
 			if pc == nil {
 				pc = new(PathCriteria)
@@ -1,6 +1,9 @@
 package db
 
 import (
+	"strconv"
+	"strings"
+
 	"github.com/quintans/faults"
 	coll "github.com/quintans/toolkit/collections"
 
@@ -12,6 +15,7 @@ type DmlCore struct {
 
 	lastType     reflect.Type
 	lastMappings map[string]*EntityProperty
+	lastShape    string
 	vals         coll.Map
 	cols         []*Column
 }
@@ -94,3 +98,84 @@ func (d *DmlCore) defineParameter(col *Column, value Tokener) (interface{}, bool
 func (d *DmlCore) GetValues() coll.Map {
 	return d.vals
 }
+
+// dmlValues are the values, and parameters, set in a DML
+type dmlValues struct {
+	vals       []*coll.KeyValue
+	parameters map[string]interface{}
+	rawIndex   int
+}
+
+// keepValues returns the values set until now, to be restored with restoreValues
+func (d *DmlCore) keepValues() dmlValues {
+	kept := dmlValues{
+		parameters: make(map[string]interface{}, len(d.parameters)),
+		rawIndex:   d.rawIndex,
+	}
+	if d.vals != nil {
+		kept.vals = d.vals.Elements()
+	}
+	for k, v := range d.parameters {
+		kept.parameters[k] = v
+	}
+	return kept
+}
+
+// restoreValues discards the values set after keepValues.
+// The parameter names are also restored, so that the same values generate the same SQL.
+func (d *DmlCore) restoreValues(kept dmlValues) {
+	d.vals = coll.NewLinkedHashMap()
+	for _, kv := range kept.vals {
+		d.vals.Put(kv.Key, kv.Value)
+	}
+	d.parameters = make(map[string]interface{}, len(kept.parameters))
+	for k, v := range kept.parameters {
+		d.parameters[k] = v
+	}
+	d.rawIndex = kept.rawIndex
+}
+
+// shape identifies the SQL generated for a submitted struct of the type typ,
+// that may check the version or not, by the values being set.
+func (d *DmlCore) shape(typ reflect.Type, versioned bool) string {
+	var sb strings.Builder
+	sb.WriteString(typ.String())
+	sb.WriteString(":")
+	sb.WriteString(strconv.FormatBool(versioned))
+	if d.vals != nil {
+		for it := d.vals.Iterator(); it.HasNext(); {
+			entry := it.Next()
+			sb.WriteString(",")
+			sb.WriteString(entry.Key.(*Column).GetAlias())
+			sb.WriteString("=")
+			if token := entry.Value.(Tokener); token.GetOperator() == TOKEN_PARAM {
+				sb.WriteString(token.GetValue().(string))
+			} else {
+				sb.WriteString(token.GetOperator())
+			}
+		}
+	}
+	return sb.String()
+}
+
+// whereKeys restricts the DML to the keys, and version, of a submitted struct.
+// The criteria are only applied, forcing a new SQL, when the shape of the statement changes.
+// In a batch, the SQL built for each shape is kept in stmts and reused by the following elements.
+func (d *DmlCore) whereKeys(criterias []*Criteria, shape string, stmts *statements) {
+	if stmts != nil {
+		// the criteria may also define parameters, with the names following the raw index
+		shape += "#" + strconv.Itoa(d.rawIndex)
+		d.criteria = nil
+		d.where(criterias)
+		d.rawSQL = stmts.sqls[shape]
+		d.lastShape = shape
+		return
+	}
+
+	if shape == d.lastShape && d.rawSQL != nil {
+		return
+	}
+	d.criteria = nil
+	d.where(criterias)
+	d.lastShape = shape
+}
@@ -43,6 +43,7 @@ var TOKEN_MIN = "MIN"
 var TOKEN_RTRIM = "RTRIM"
 var TOKEN_UPPER = "UPPER"
 var TOKEN_LOWER = "LOWER"
+var TOKEN_GROUPING = "GROUPING"
 
 var TOKEN_MULTIPLY = "MULTIPLY"
 var TOKEN_DIVIDE = "DIVIDE"
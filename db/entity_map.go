@@ -2,14 +2,14 @@ package db
 
 import (
 	coll "github.com/quintans/toolkit/collections"
-	"github.com/quintans/toolkit/ext"
 )
 
 const FK_NAV_SEP = "."
 
-// entity mapping
+// entity mapping of the default schema
 var Tables = coll.NewLinkedHashMap()
 
+// AddEntity registers the table in the default schema
 func AddEntity(table *Table) {
-	Tables.Put(ext.Str(table.Alias), table)
+	_ = DefaultSchema.register(table, table.Alias)
 }
@@ -12,6 +12,8 @@ type EntityProperty struct {
 }
 
 type StructProperty struct {
+	// the name of the struct field, used to find its marks
+	field     string
 	Type      reflect.Type
 	InnerType reflect.Type
 	Omit      bool
@@ -168,14 +168,20 @@ func (e *EntityTransformer) Transform(rows *sql.Rows) (interface{}, error) {
 	if err := rows.Scan(rowData...); err != nil {
 		return nil, faults.Wrap(err)
 	}
+	e.Query.captureKeyset(rowData)
 
 	if _, err := e.Overrider.ToEntity(rowData, val, e.Properties, nil); err != nil {
 		return nil, faults.Wrap(err)
 	}
 
+	found := false
+	if identities := identityMapOf(e.Query.GetDb()); identities != nil {
+		val, found = identities.resolve(e.Query.GetTable(), e.Properties, "", val)
+	}
+
 	instance := val.Interface()
 	// post trigger
-	if t, isT := instance.(PostRetriever); isT {
+	if t, isT := instance.(PostRetriever); isT && !found {
 		t.PostRetrieve(e.Query.GetDb())
 	}
 
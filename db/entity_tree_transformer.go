@@ -94,8 +94,9 @@ func (e *EntityTreeTransformer) Transform(rows *sql.Rows) (interface{}, error) {
 	if err := rows.Scan(rowData...); err != nil {
 		return nil, faults.Wrap(err)
 	}
+	e.Query.captureKeyset(rowData)
 
-	instance, err := e.transformEntity(rowData, val, alias)
+	instance, err := e.transformEntity(rowData, val, e.Query.GetTable(), alias)
 	if err != nil {
 		return nil, faults.Wrap(err)
 	}
@@ -152,6 +153,7 @@ func (e *EntityTreeTransformer) InitFullRowData(
 func (e *EntityTreeTransformer) transformEntity(
 	row []interface{},
 	parent reflect.Value,
+	table *Table,
 	alias string,
 ) (interface{}, error) {
 	var valid bool
@@ -179,6 +181,13 @@ func (e *EntityTreeTransformer) transformEntity(
 				if err != nil {
 					return nil, faults.Wrap(err)
 				} else if valid {
+					// the same instance across queries of the transaction
+					if identities := identityMapOf(e.Query.GetDb()); identities != nil {
+						var found bool
+						if parent, found = identities.resolve(table, lastProps, alias+".", parent); found {
+							hasher = parent.Interface().(tk.Hasher)
+						}
+					}
 					e.entities.Put(hasher, hasher)
 				} else {
 					hasher = nil
@@ -233,10 +242,13 @@ func (e *EntityTreeTransformer) transformEntity(
 					subType = bp.Type
 				}
 				var fkAlias string
+				var fkTable *Table
 				if fk.IsMany2Many() {
 					fkAlias = fk.ToM2M.GetAliasTo()
+					fkTable = fk.ToM2M.GetTableTo()
 				} else {
 					fkAlias = fk.GetAliasTo()
+					fkTable = fk.GetTableTo()
 				}
 
 				var childVal reflect.Value
@@ -245,7 +257,7 @@ func (e *EntityTreeTransformer) transformEntity(
 				} else {
 					childVal = reflect.Zero(subType)
 				}
-				child, err := e.transformEntity(row, childVal, fkAlias)
+				child, err := e.transformEntity(row, childVal, fkTable, fkAlias)
 				if err != nil {
 					return nil, faults.Wrap(err)
 				} else if child != nil {
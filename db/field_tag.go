@@ -0,0 +1,56 @@
+package db
+
+import "strings"
+
+// FieldTag holds the values of the sql tag of a struct field, like `sql:"omit,converter=json"`
+type FieldTag struct {
+	// Omit ignores the field in updates if its value is the zero value
+	Omit bool
+	// Embedded maps the fields of the struct field as if they were fields of the parent struct
+	Embedded bool
+	// Key marks the field as mapped to a key column. Only used by generators.
+	Key bool
+	// Version marks the field as mapped to the version column. Only used by generators.
+	Version bool
+	// Converter is the name of the registered converter of the field
+	Converter string
+	// Col is the alias of the column mapped to the field, used as is, overriding the naming strategy
+	Col string
+	// Column is the name of the column mapped to the field, overriding the naming strategy
+	Column string
+	// FK is the name of the struct whose key is referenced by the field. Only used by generators.
+	FK string
+}
+
+// ParseFieldTag parses the value of the sql tag of a struct field
+func ParseFieldTag(value string) FieldTag {
+	tag := FieldTag{}
+	if value == "" {
+		return tag
+	}
+	for _, s := range strings.Split(value, ",") {
+		v := strings.TrimSpace(s)
+		switch v {
+		case sqlOmissionVal:
+			tag.Omit = true
+		case sqlEmbededVal, sqlEmbeddedVal:
+			tag.Embedded = true
+		case sqlKeyVal:
+			tag.Key = true
+		case sqlVersionVal:
+			tag.Version = true
+		default:
+			switch {
+			case strings.HasPrefix(v, converterTag):
+				tag.Converter = v[len(converterTag):]
+			case strings.HasPrefix(v, colTag):
+				tag.Col = v[len(colTag):]
+			case strings.HasPrefix(v, columnTag):
+				tag.Column = v[len(columnTag):]
+			case strings.HasPrefix(v, fkTag):
+				tag.FK = v[len(fkTag):]
+			}
+		}
+	}
+	return tag
+}
@@ -0,0 +1,37 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFieldTag(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  FieldTag
+	}{
+		{name: "empty", value: "", want: FieldTag{}},
+		{name: "omit", value: "omit", want: FieldTag{Omit: true}},
+		{name: "embedded", value: "embedded", want: FieldTag{Embedded: true}},
+		{name: "embeded", value: "embeded", want: FieldTag{Embedded: true}},
+		{name: "key", value: "key", want: FieldTag{Key: true}},
+		{name: "version", value: "version", want: FieldTag{Version: true}},
+		{name: "converter", value: "converter=json", want: FieldTag{Converter: "json"}},
+		{name: "col", value: "col=Title", want: FieldTag{Col: "Title"}},
+		{name: "column", value: "column=PUB_NAME", want: FieldTag{Column: "PUB_NAME"}},
+		{name: "fk", value: "fk=Publisher", want: FieldTag{FK: "Publisher"}},
+		{
+			name:  "combined",
+			value: "omit, converter=json ,column=BOOK_ID,fk=Book",
+			want:  FieldTag{Omit: true, Converter: "json", Column: "BOOK_ID", FK: "Book"},
+		},
+		{name: "unknown", value: "other,size=10", want: FieldTag{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, ParseFieldTag(tt.value))
+		})
+	}
+}
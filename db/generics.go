@@ -0,0 +1,116 @@
+package db
+
+import (
+	"database/sql"
+	"reflect"
+
+	"github.com/quintans/faults"
+)
+
+// List executes the query and returns the results as a slice of T,
+// where T is a struct or a pointer to a struct.
+//
+// This function does not create a tree of related instances.
+//
+// ex:
+//   books, err := db.List[*Book](store.Query(BOOK).All())
+func List[T any](query *Query) ([]T, error) {
+	if query.err != nil {
+		return nil, query.err
+	}
+
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	ptr := typ.Kind() == reflect.Ptr
+	if !ptr {
+		typ = reflect.PtrTo(typ)
+	}
+	if typ.Elem().Kind() != reflect.Struct {
+		return nil, faults.Errorf("expected a struct or a pointer to a struct. got %s", typ.Elem())
+	}
+
+	result := []T{}
+	returner := func(val reflect.Value) reflect.Value {
+		if !ptr {
+			val = val.Elem()
+		}
+		result = append(result, val.Interface().(T))
+		return val
+	}
+
+	if _, err := query.list(NewEntityFactoryTransformer(query, typ, returner)); err != nil {
+		return nil, faults.Wrap(err)
+	}
+	return result, nil
+}
+
+// One executes the query and returns the first result as T,
+// where T is a struct or a pointer to a struct.
+// The boolean is false if no result was found.
+func One[T any](query *Query) (T, bool, error) {
+	var zero T
+	if query.err != nil {
+		return zero, false, query.err
+	}
+
+	oldMax := query.limit
+	query.Limit(1)
+	defer query.Limit(oldMax)
+
+	list, err := List[T](query)
+	if err != nil {
+		return zero, false, faults.Wrap(err)
+	}
+	if len(list) == 0 {
+		return zero, false, nil
+	}
+	return list[0], true, nil
+}
+
+// Retrieve returns the entity of type T with the supplied keys,
+// where T is a struct or a pointer to a struct mapped to a table.
+// The boolean is false if no entity was found.
+//
+// ex:
+//   book, found, err := db.Retrieve[*Book](store, 1)
+func Retrieve[T any](store IDb, keys ...interface{}) (T, bool, error) {
+	var entity T
+	var instance interface{} = &entity
+	if typ := reflect.TypeOf((*T)(nil)).Elem(); typ.Kind() == reflect.Ptr {
+		entity = reflect.New(typ.Elem()).Interface().(T)
+		instance = entity
+	}
+
+	found, err := store.Retrieve(instance, keys...)
+	if err != nil || !found {
+		var zero T
+		return zero, false, faults.Wrap(err)
+	}
+	return entity, true, nil
+}
+
+// ListInto executes a query with a single column and returns the values as a slice of T,
+// where T is a type that can be scanned, like a primitive, a pointer to a primitive or a sql.Scanner.
+//
+// ex:
+//   names, err := db.ListInto[string](store.Query(BOOK).Column(BOOK_C_NAME))
+func ListInto[T any](query *Query) ([]T, error) {
+	if query.err != nil {
+		return nil, query.err
+	}
+
+	result := []T{}
+	var value T
+	err := query.listClosure(func(rows *sql.Rows) error {
+		if err := rows.Scan(&value); err != nil {
+			return faults.Wrap(err)
+		}
+		result = append(result, value)
+		// pointers must not be shared between rows
+		value = *new(T)
+		return nil
+	})
+	if err != nil {
+		return nil, faults.Wrap(err)
+	}
+	return result, nil
+}
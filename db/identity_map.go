@@ -0,0 +1,122 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// TmWithIdentityMap attaches an identity map to the store of each transaction.
+// Within the transaction, queries return the same struct instance for the same table and key
+// and Retrieve is answered from the map, without a round trip to the database.
+func TmWithIdentityMap() func(*TransactionManager) {
+	return func(t *TransactionManager) {
+		t.identityMap = true
+	}
+}
+
+type identityKey struct {
+	table string
+	typ   reflect.Type
+	key   string
+}
+
+// identityMap holds, by table and key, the struct pointers loaded in a transaction
+type identityMap struct {
+	entities map[identityKey]reflect.Value
+}
+
+func newIdentityMap() *identityMap {
+	return &identityMap{
+		entities: map[identityKey]reflect.Value{},
+	}
+}
+
+// identityMapOf returns the identity map of the transaction of the store, or nil if there is none
+func identityMapOf(store IDb) *identityMap {
+	if tx, ok := store.GetConnection().(*MyTx); ok {
+		return tx.identities
+	}
+	return nil
+}
+
+// resolve returns the instance already in the map with the same key of the supplied struct pointer.
+// If there is none, the supplied instance is added to the map, if it holds all the columns of the table.
+func (m *identityMap) resolve(table *Table, props map[string]*EntityProperty, prefix string, instance reflect.Value) (reflect.Value, bool) {
+	key, ok := identityOf(table, props, prefix, instance)
+	if !ok {
+		return instance, false
+	}
+	if cached, ok := m.entities[key]; ok {
+		return cached, true
+	}
+
+	// partial entities are not kept
+	for e := table.GetColumns().Enumerator(); e.HasNext(); {
+		column := e.Next().(*Column)
+		bp := props[prefix+capFirst(column.GetAlias())]
+		if bp != nil && !bp.Omit && bp.Position == 0 {
+			return instance, false
+		}
+	}
+	m.entities[key] = instance
+	return instance, false
+}
+
+// sync updates the instance in the map with the values of the supplied struct pointer
+func (m *identityMap) sync(table *Table, props map[string]*EntityProperty, instance reflect.Value) {
+	key, ok := identityOf(table, props, "", instance)
+	if !ok {
+		return
+	}
+	if cached, ok := m.entities[key]; ok {
+		if cached.Pointer() != instance.Pointer() {
+			cached.Elem().Set(instance.Elem())
+		}
+		return
+	}
+	m.entities[key] = instance
+}
+
+func (m *identityMap) remove(table *Table, props map[string]*EntityProperty, instance reflect.Value) {
+	if key, ok := identityOf(table, props, "", instance); ok {
+		delete(m.entities, key)
+	}
+}
+
+func (m *identityMap) get(table *Table, typ reflect.Type, keys []interface{}) (reflect.Value, bool) {
+	values := make([]string, len(keys))
+	for k, v := range keys {
+		values[k] = fmt.Sprint(reflect.Indirect(reflect.ValueOf(v)).Interface())
+	}
+	cached, ok := m.entities[identityKey{table.GetName(), typ, strings.Join(values, ",")}]
+	return cached, ok
+}
+
+// clear empties the map, when it is no longer possible to know which entities are stale
+func (m *identityMap) clear() {
+	m.entities = map[identityKey]reflect.Value{}
+}
+
+// identityOf returns the identity of a struct pointer, from the values of the key columns.
+// It is not valid if the instance is not a struct pointer or if any key is missing.
+func identityOf(table *Table, props map[string]*EntityProperty, prefix string, instance reflect.Value) (identityKey, bool) {
+	if table == nil || instance.Kind() != reflect.Ptr || instance.IsNil() || table.GetKeyColumns().Size() == 0 {
+		return identityKey{}, false
+	}
+
+	values := []string{}
+	for e := table.GetKeyColumns().Enumerator(); e.HasNext(); {
+		column := e.Next().(*Column)
+		bp := props[prefix+capFirst(column.GetAlias())]
+		if bp == nil {
+			return identityKey{}, false
+		}
+		v := bp.Get(instance)
+		if !v.IsValid() || v.Kind() == reflect.Ptr && v.IsNil() {
+			return identityKey{}, false
+		}
+		values = append(values, fmt.Sprint(reflect.Indirect(v).Interface()))
+	}
+	return identityKey{table.GetName(), instance.Type().Elem(), strings.Join(values, ",")}, true
+}
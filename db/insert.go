@@ -163,7 +163,8 @@ func (i *Insert) Submit(instance interface{}) (int64, error) {
 		} else {
 			bp := mappings[column.GetAlias()]
 			if bp != nil {
-				marked := !useMarks || marks[column.GetAlias()]
+				// the marks are the field names, that may differ from the column alias
+				marked := !useMarks || marks[bp.field]
 				v := bp.Get(elem)
 				if v.IsValid() && (!useMarks || marked) {
 					if v.Kind() == reflect.Ptr && v.IsNil() {
@@ -258,6 +259,17 @@ func (i *Insert) getCachedSql() *RawSql {
 	return i.rawSQL
 }
 
+// ToSQL returns the SQL, and its arguments, that would be executed by the insert, without executing it.
+//
+// Keys generated by the database, before the insert, are not included.
+func (i *Insert) ToSQL() (Statement, error) {
+	if i.err != nil {
+		return Statement{}, i.err
+	}
+
+	return i.validStatement(true, i.getCachedSql)
+}
+
 // returns the last inserted id
 func (i *Insert) Execute() (int64, error) {
 	if i.err != nil {
@@ -268,6 +280,9 @@ func (i *Insert) Execute() (int64, error) {
 	if table.PreInsertTrigger != nil {
 		table.PreInsertTrigger(i)
 	}
+	if err := i.validate(true); err != nil {
+		return 0, err
+	}
 
 	var err error
 	var lastId int64
@@ -276,6 +291,7 @@ func (i *Insert) Execute() (int64, error) {
 
 	var sql string
 	var params []interface{}
+	markWrite(i.db)
 	switch strategy {
 	case AUTOKEY_BEFORE:
 		if i.returnId && !i.HasKeyValue && singleKeyColumn != nil {
@@ -0,0 +1,295 @@
+package db
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/gob"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/quintans/faults"
+	"github.com/quintans/toolkit/ext"
+)
+
+const (
+	KEYSET_PARAM  = "KEYSET_PARAM"
+	KEYSET_COLUMN = "KEYSET"
+)
+
+func init() {
+	// values scanned into interface{} are basic types, already known by gob, or time.Time
+	gob.Register(time.Time{})
+}
+
+// Cursors holds the opaque cursors of a page of results obtained with keyset pagination.
+//
+// Next is to be used with After to get the following page and Prev is to be used with Before to get the preceding page.
+// Both are empty if the page has no results.
+type Cursors struct {
+	Prev string
+	Next string
+}
+
+type keyset struct {
+	before   bool
+	criteria *Criteria
+	// the columns used to build the cursors
+	columns []Tokener
+	// positions (1 based) of the keyset columns in the last generated SQL
+	positions []int
+	first     []interface{}
+	last      []interface{}
+}
+
+func newKeyset(q *Query, cursor string, before bool) (*keyset, error) {
+	if len(q.orders) == 0 {
+		return nil, faults.New("keyset pagination requires at least one order")
+	}
+
+	k := &keyset{before: before}
+	tokens := make([]Tokener, len(q.orders))
+	for i, o := range q.orders {
+		var token Tokener
+		if o.GetHolder() != nil {
+			token = o.GetHolder().Clone().(Tokener)
+		} else {
+			for _, c := range q.Columns {
+				if c.GetAlias() == o.GetAlias() {
+					token = c.Clone().(Tokener)
+					break
+				}
+			}
+			if token == nil {
+				return nil, faults.Errorf("the order alias '%s' does not match any column", o.GetAlias())
+			}
+		}
+		tokens[i] = token
+
+		column := token.Clone().(Tokener)
+		column.SetAlias(KEYSET_COLUMN + strconv.Itoa(i+1))
+		k.columns = append(k.columns, column)
+	}
+
+	if cursor == "" {
+		return k, nil
+	}
+
+	values, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, faults.Wrap(err)
+	}
+	if len(values) != len(q.orders) {
+		return nil, faults.Errorf("the cursor has %d values but the query has %d orders", len(values), len(q.orders))
+	}
+
+	// (a, b) > (x, y) is expanded to: a > x OR a = x AND b > y
+	// so that it works with mixed directions and with every database
+	ors := make([]*Criteria, len(tokens))
+	for i, token := range tokens {
+		param := KEYSET_PARAM + "_" + strconv.Itoa(i+1)
+		q.SetParameter(param, values[i])
+
+		ands := make([]*Criteria, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, Matches(tokens[j], Param(KEYSET_PARAM+"_"+strconv.Itoa(j+1))))
+		}
+		if q.orders[i].IsAsc() != before {
+			ands = append(ands, Greater(token, Param(param)))
+		} else {
+			ands = append(ands, Lesser(token, Param(param)))
+		}
+		ors[i] = And(ands...)
+	}
+	k.criteria = Or(ors...)
+
+	return k, nil
+}
+
+// apply temporarily changes the query so that the generated SQL has the keyset restriction,
+// the keyset columns and, when going backwards, the reversed orders.
+// The returned function restores the query.
+func (k *keyset) apply(q *Query) func() {
+	criteria, columns, orders := q.criteria, q.Columns, q.orders
+
+	if k.criteria != nil {
+		if q.criteria == nil {
+			q.criteria = k.criteria
+		} else {
+			q.criteria = And(q.criteria, k.criteria)
+		}
+	}
+
+	cols := make([]Tokener, len(columns), len(columns)+len(k.columns))
+	copy(cols, columns)
+	k.positions = make([]int, len(k.columns))
+	for i, c := range k.columns {
+		cols = append(cols, c)
+		k.positions[i] = len(cols)
+	}
+	q.Columns = cols
+
+	if k.before {
+		reversed := make([]*Order, len(orders))
+		for i, o := range orders {
+			r := *o
+			r.asc = !o.asc
+			reversed[i] = &r
+		}
+		q.orders = reversed
+	}
+
+	return func() {
+		q.criteria, q.Columns, q.orders = criteria, columns, orders
+	}
+}
+
+func (k *keyset) capture(row []interface{}) {
+	values := make([]interface{}, len(k.positions))
+	for i, pos := range k.positions {
+		if pos > len(row) {
+			return
+		}
+		v := row[pos-1]
+		if a, ok := v.(*ext.Any); ok {
+			v = a.Val
+		} else if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr {
+			// the column was also mapped to a struct field
+			for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+				rv = rv.Elem()
+			}
+			if rv.Kind() == reflect.Ptr {
+				v = nil
+			} else {
+				v = rv.Interface()
+			}
+		}
+		// the driver may reuse the buffer
+		if b, ok := v.([]byte); ok {
+			v = append([]byte(nil), b...)
+		}
+		values[i] = v
+	}
+	if k.first == nil {
+		k.first = values
+	}
+	k.last = values
+}
+
+func (k *keyset) reset() {
+	k.first = nil
+	k.last = nil
+}
+
+func encodeCursor(values []interface{}) (string, error) {
+	if values == nil {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(values); err != nil {
+		return "", faults.Wrap(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func decodeCursor(cursor string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, faults.Errorf("invalid cursor: %w", err)
+	}
+	var values []interface{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&values); err != nil {
+		return nil, faults.Errorf("invalid cursor: %w", err)
+	}
+	return values, nil
+}
+
+// After restricts the results to the ones that come after the supplied cursor,
+// according to the orders already defined in the query (keyset pagination).
+//
+// An empty cursor means the first page.
+// For stable results the orders should end with a unique column, like the key.
+// Order columns must not be nullable.
+//
+// ex:
+//   var books []*Book
+//   cursors, err := store.Query(BOOK).
+//     All().
+//     Order(BOOK_C_PRICE).Desc().
+//     Order(BOOK_C_ID).
+//     After(cursor).
+//     Limit(10).
+//     ListWithCursors(&books)
+func (q *Query) After(cursor string) *Query {
+	return q.keysetFrom(cursor, false)
+}
+
+// Before restricts the results to the ones that come before the supplied cursor,
+// according to the orders already defined in the query (keyset pagination).
+//
+// An empty cursor means the last page.
+// The database is queried with the orders reversed, so ListWithCursors should be used
+// to have the results in the natural order.
+func (q *Query) Before(cursor string) *Query {
+	return q.keysetFrom(cursor, true)
+}
+
+func (q *Query) keysetFrom(cursor string, before bool) *Query {
+	if q.err != nil {
+		return q
+	}
+
+	k, err := newKeyset(q, cursor, before)
+	if err != nil {
+		return &Query{err: err}
+	}
+	q.keyset = k
+	q.rawSQL = nil
+
+	return q
+}
+
+// ListWithCursors executes a query defined with After or Before, putting the result in the supplied slice,
+// like List, and returns the cursors to navigate to the neighbour pages.
+//
+// The argument must be a slice like *[]<*>struct.
+func (q *Query) ListWithCursors(target interface{}) (Cursors, error) {
+	if q.err != nil {
+		return Cursors{}, q.err
+	}
+	if q.keyset == nil {
+		return Cursors{}, faults.New("ListWithCursors requires the use of After or Before")
+	}
+
+	arr := reflect.ValueOf(target)
+	if arr.Kind() != reflect.Ptr || arr.Elem().Kind() != reflect.Slice {
+		return Cursors{}, faults.Errorf("expected a slice of type *[]<*>struct. got %T", target)
+	}
+
+	k := q.keyset
+	k.reset()
+	if err := q.List(target); err != nil {
+		return Cursors{}, faults.Wrap(err)
+	}
+
+	first, last := k.first, k.last
+	if k.before {
+		// results came in the reverse order
+		slice := arr.Elem()
+		swap := reflect.Swapper(slice.Interface())
+		for i, j := 0, slice.Len()-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+		first, last = last, first
+	}
+
+	var cursors Cursors
+	var err error
+	if cursors.Prev, err = encodeCursor(first); err != nil {
+		return Cursors{}, faults.Wrap(err)
+	}
+	if cursors.Next, err = encodeCursor(last); err != nil {
+		return Cursors{}, faults.Wrap(err)
+	}
+	return cursors, nil
+}
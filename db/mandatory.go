@@ -0,0 +1,87 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var _ error = &MandatoryFail{}
+
+// MandatoryFail is returned, before executing an insert or an update,
+// when mandatory columns are missing or have a nil value
+type MandatoryFail struct {
+	Table string
+	// names of the offending columns
+	Columns []string
+}
+
+func (m *MandatoryFail) Error() string {
+	return fmt.Sprintf("mandatory columns of %s without value: %s", m.Table, strings.Join(m.Columns, ", "))
+}
+
+// checkMandatory verifies that the mandatory columns being set do not have a nil value.
+// With all, as for an insert, the mandatory columns that are not being set are also reported.
+// Key and version columns are not verified, since their values can be supplied by goSQL or by the database.
+func (d *DmlCore) checkMandatory(all bool) error {
+	var columns []string
+	for e := d.table.GetColumns().Enumerator(); e.HasNext(); {
+		column := e.Next().(*Column)
+		if !column.IsMandatory() || column.IsKey() || column.IsVersion() {
+			continue
+		}
+
+		var token Tokener
+		if d.vals != nil {
+			if v, ok := d.vals.Get(column); ok {
+				token = v.(Tokener)
+			}
+		}
+		if token == nil {
+			if all {
+				columns = append(columns, column.GetName())
+			}
+			continue
+		}
+		if d.isNilToken(token) {
+			columns = append(columns, column.GetName())
+		}
+	}
+
+	if len(columns) > 0 {
+		return &MandatoryFail{
+			Table:   d.table.GetName(),
+			Columns: columns,
+		}
+	}
+	return nil
+}
+
+// isNilToken returns true if the token is NULL or a parameter with a nil value
+func (d *DmlCore) isNilToken(token Tokener) bool {
+	switch token.GetOperator() {
+	case TOKEN_NULL:
+		return true
+	case TOKEN_PARAM:
+		value, ok := d.parameters[token.GetValue().(string)]
+		return ok && isNilValue(value)
+	}
+	return false
+}
+
+func isNilValue(value interface{}) bool {
+	value, err := driverValue(value)
+	// an error will be reported when executing
+	if err != nil {
+		return false
+	}
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"strings"
+
+	"github.com/quintans/goSQL/dbx"
+)
+
+// NamingStrategy maps the name of a struct field to the alias of the column it holds.
+// The alias of a column is, by default, the camel case of its name, like PubName for PUB_NAME.
+type NamingStrategy interface {
+	FieldAlias(field string) string
+}
+
+// NamingFunc is a function that can be used as a NamingStrategy
+type NamingFunc func(field string) string
+
+func (f NamingFunc) FieldAlias(field string) string {
+	return f(field)
+}
+
+// DefaultNaming matches a field with the alias equal to the capitalized field name
+var DefaultNaming NamingStrategy = NamingFunc(capFirst)
+
+// SnakeCaseNaming matches a field with the alias of the snake case of the field name,
+// so that acronyms are found, like UserID for the column USER_ID (alias UserId)
+var SnakeCaseNaming NamingStrategy = NamingFunc(func(field string) string {
+	return dbx.ToCamelCase(dbx.ToSnakeCase(field))
+})
+
+// PrefixNaming matches a field with the alias of a legacy column with the prefix,
+// like Name for the column PUB_NAME (alias PubName) with the prefix PUB_
+func PrefixNaming(prefix string) NamingStrategy {
+	prefix = strings.ToUpper(prefix)
+	return NamingFunc(func(field string) string {
+		return dbx.ToCamelCase(prefix + dbx.ToSnakeCase(field))
+	})
+}
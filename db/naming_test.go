@@ -0,0 +1,32 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldAlias(t *testing.T) {
+	tests := []struct {
+		name   string
+		naming NamingStrategy
+		field  string
+		tag    string
+		want   string
+	}{
+		{name: "default", naming: DefaultNaming, field: "publisherId", want: "PublisherId"},
+		{name: "snake case", naming: SnakeCaseNaming, field: "PublisherID", want: "PublisherId"},
+		{name: "prefix", naming: PrefixNaming("pub_"), field: "Name", want: "PubName"},
+		{name: "column", naming: SnakeCaseNaming, field: "Name", tag: "column=PUB_NAME", want: "PubName"},
+		{name: "lower case column", naming: DefaultNaming, field: "Name", tag: "column=pub_name", want: "PubName"},
+		{name: "col", naming: SnakeCaseNaming, field: "Title", tag: "col=Name", want: "Name"},
+		{name: "upper case col", naming: DefaultNaming, field: "Link", tag: "col=URL", want: "URL"},
+		{name: "upper case column", naming: DefaultNaming, field: "Link", tag: "column=URL", want: "Url"},
+		{name: "col before column", naming: DefaultNaming, field: "Name", tag: "column=PUB_NAME,col=Title", want: "Title"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, fieldAlias(tt.naming, tt.field, ParseFieldTag(tt.tag)))
+		})
+	}
+}
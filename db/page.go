@@ -0,0 +1,106 @@
+package db
+
+import (
+	"reflect"
+
+	"github.com/quintans/faults"
+)
+
+const COUNT_ALIAS = "cnt"
+
+// Page is the result of a paginated query
+type Page struct {
+	// Items is the slice passed to ListPage
+	Items interface{}
+	// Total is the number of records without pagination
+	Total int64
+	Skip  int64
+	Limit int64
+}
+
+// ListPage executes the query, putting the result in the supplied slice, like List,
+// and returns a page with the total of records that the query would return without pagination.
+//
+// The total is obtained from the same query, without orders and pagination, wrapped in a SELECT COUNT(*).
+// The count is not executed if the total can be inferred from the returned items.
+//
+// Queries fetching associations are not supported, since the pagination applies to the joined rows
+// and not to the records of the driving table.
+//
+// The argument must be a slice like *[]<*>struct.
+//
+// ex:
+//   var books []*Book
+//   page, err := store.Query(BOOK).
+//     All().
+//     Order(BOOK_C_NAME).
+//     Skip(20).
+//     Limit(10).
+//     ListPage(&books)
+func (q *Query) ListPage(target interface{}) (Page, error) {
+	if q.err != nil {
+		return Page{}, q.err
+	}
+
+	arr := reflect.ValueOf(target)
+	if arr.Kind() != reflect.Ptr || arr.Elem().Kind() != reflect.Slice {
+		return Page{}, faults.Errorf("expected a slice of type *[]<*><struct|primitive>. got %T", target)
+	}
+	for _, join := range q.joins {
+		if join.fetch {
+			return Page{}, faults.New("ListPage does not support queries fetching associations. Use Join() instead of Fetch()")
+		}
+	}
+
+	if err := q.List(target); err != nil {
+		return Page{}, faults.Wrap(err)
+	}
+
+	size := int64(arr.Elem().Len())
+	page := Page{
+		Items: arr.Elem().Interface(),
+		Skip:  q.skip,
+		Limit: q.limit,
+	}
+	// a page that is not full is the last one
+	if q.limit == 0 || (size > 0 || q.skip == 0) && size < q.limit {
+		page.Total = q.skip + size
+		return page, nil
+	}
+
+	total, err := q.countAll()
+	if err != nil {
+		return Page{}, faults.Wrap(err)
+	}
+	page.Total = total
+	return page, nil
+}
+
+// countAll counts the records returned by the query, ignoring orders and pagination.
+func (q *Query) countAll() (int64, error) {
+	// if no columns were added, add all columns of the driving table
+	if len(q.Columns) == 0 {
+		q.All()
+	}
+	// if the discriminator conditions have not yet been processed, apply them now
+	if q.discriminatorCriterias != nil && q.criteria == nil {
+		q.DmlBase.where(nil)
+	}
+
+	sub := NewQuery(q.db, q.table)
+	sub.Copy(q)
+	sub.orders = nil
+	sub.skip = 0
+	sub.limit = 0
+	sub.keyset = nil
+	sub.rawSQL = nil
+
+	var total int64
+	_, err := NewQueryQueryAs(sub, COUNT_ALIAS).
+		CountAll().
+		SelectInto(&total)
+	if err != nil {
+		return 0, faults.Wrap(err)
+	}
+	return total, nil
+}
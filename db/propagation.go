@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+
+	"github.com/quintans/faults"
+)
+
+// Propagation defines how TransactionCtx behaves regarding the transaction in the context
+type Propagation int
+
+const (
+	// PROPAGATION_REQUIRED joins the transaction in the context or starts a new one
+	PROPAGATION_REQUIRED Propagation = iota
+	// PROPAGATION_REQUIRES_NEW always starts a new transaction. The transaction in the context is suspended.
+	PROPAGATION_REQUIRES_NEW
+	// PROPAGATION_NESTED runs inside a savepoint of the transaction in the context or starts a new one
+	PROPAGATION_NESTED
+	// PROPAGATION_SUPPORTS joins the transaction in the context or runs without a transaction
+	PROPAGATION_SUPPORTS
+)
+
+type storeKey struct{}
+
+// contextStore returns the store of the transaction in the context, if any
+func contextStore(ctx context.Context) IDb {
+	store, ok := ctx.Value(storeKey{}).(IDb)
+	if !ok {
+		return nil
+	}
+	if _, inTx := store.GetConnection().(*MyTx); !inTx {
+		return nil
+	}
+	return store
+}
+
+// withStore returns a context holding the store, bound to the store, and the store
+func withStore(ctx context.Context, store IDb) (context.Context, IDb) {
+	ctx = context.WithValue(ctx, storeKey{}, store)
+	return ctx, store.WithContext(ctx)
+}
+
+// TransactionCtx runs the handler according to the propagation and the transaction stored in the context.
+// The context passed to the handler holds the transaction, so it can be passed to other services
+// that also call TransactionCtx, without passing the store around.
+//
+// ex:
+//   tm.TransactionCtx(ctx, db.PROPAGATION_REQUIRED, func(ctx context.Context, store db.IDb) error {
+//     ...
+//     return otherService.Do(ctx)
+//   })
+func (t *TransactionManager) TransactionCtx(ctx context.Context, propagation Propagation, handler func(ctx context.Context, db IDb) error) error {
+	outer := contextStore(ctx)
+	newTx := func() error {
+		return t.newTransaction(ctx, nil, func(store IDb) error {
+			return handler(withStore(ctx, store))
+		})
+	}
+
+	switch propagation {
+	case PROPAGATION_REQUIRED:
+		if outer != nil {
+			return handler(ctx, outer)
+		}
+		return newTx()
+	case PROPAGATION_REQUIRES_NEW:
+		return newTx()
+	case PROPAGATION_NESTED:
+		if outer != nil {
+			return nestedTransaction(outer, func(store IDb) error {
+				return handler(ctx, store)
+			})
+		}
+		return newTx()
+	case PROPAGATION_SUPPORTS:
+		if outer != nil {
+			return handler(ctx, outer)
+		}
+		return t.NoTransaction(func(store IDb) error {
+			return handler(withStore(ctx, store))
+		})
+	default:
+		return faults.Errorf("unknown transaction propagation %d", propagation)
+	}
+}
+
+// TransactionCtx runs the handler in the store of the enclosing transaction, as with Transaction,
+// with PROPAGATION_NESTED creating a savepoint.
+// A new transaction cannot be started, so PROPAGATION_REQUIRES_NEW is not supported.
+func (t HollowTransactionManager) TransactionCtx(ctx context.Context, propagation Propagation, handler func(ctx context.Context, db IDb) error) error {
+	ctx = context.WithValue(ctx, storeKey{}, t.db)
+	switch propagation {
+	case PROPAGATION_REQUIRED, PROPAGATION_SUPPORTS:
+		return handler(ctx, t.db)
+	case PROPAGATION_NESTED:
+		return nestedTransaction(t.db, func(store IDb) error {
+			return handler(ctx, store)
+		})
+	case PROPAGATION_REQUIRES_NEW:
+		return faults.New("HollowTransactionManager is not able to start a new transaction")
+	default:
+		return faults.Errorf("unknown transaction propagation %d", propagation)
+	}
+}
@@ -15,6 +15,15 @@ type Group struct {
 	Token    Tokener
 }
 
+type GroupingType int
+
+const (
+	GROUPING_SIMPLE GroupingType = iota
+	GROUPING_ROLLUP
+	GROUPING_CUBE
+	GROUPING_SETS
+)
+
 const (
 	OFFSET_PARAM = "OFFSET_PARAM"
 	LIMIT_PARAM  = "LIMIT_PARAM"
@@ -31,16 +40,22 @@ type Query struct {
 	subQuery      *Query
 	subQueryAlias string
 	distinct      bool
+	distinctOn    []Tokener
 
 	orders []*Order
 	unions []*Union
 	// saves position of columnHolder
-	groupBy   []int
-	having    *Criteria
-	skip      int64
-	limit     int64
-	lastToken Tokener
-	lastOrder *Order
+	groupBy  []int
+	grouping GroupingType
+	// positions of the columns of each grouping set
+	groupingSets [][]int
+	having       *Criteria
+	skip         int64
+	limit        int64
+	lastToken    Tokener
+	lastOrder    *Order
+	keyset       *keyset
+	onReplica    bool
 
 	err error
 }
@@ -48,9 +63,32 @@ type Query struct {
 func NewQuery(db IDb, table *Table) *Query {
 	this := new(Query)
 	this.DmlBase.init(db, table)
+	// logically deleted rows are filtered out like a discriminator
+	if notDeleted := table.notDeletedCriteria(); notDeleted != nil {
+		this.discriminatorCriterias = append(this.discriminatorCriterias, notDeleted)
+	}
 	return this
 }
 
+// IncludeDeleted also returns the rows that are logically deleted, of the driving table and of the joined tables.
+// It must be called before Where and before any join.
+func (q *Query) IncludeDeleted() *Query {
+	if q.err != nil {
+		return q
+	}
+	if q.criteria != nil || len(q.joins) > 0 {
+		q.err = faults.New("IncludeDeleted must be called before Where and before any join")
+		return q
+	}
+
+	q.includeDeleted = true
+	if q.table != nil {
+		q.discriminatorCriterias = q.table.GetCriterias()
+	}
+	q.rawSQL = nil
+	return q
+}
+
 func (q *Query) Alias(alias string) *Query {
 	q.alias(alias)
 	return q
@@ -65,6 +103,7 @@ func NewQueryQueryAs(subquery *Query, subQueryAlias string) *Query {
 	this.init(subquery.db, nil)
 	this.subQuery = subquery
 	this.subQueryAlias = subQueryAlias
+	this.onReplica = subquery.onReplica
 	// copy the parameters of the subquery to the main query
 	for k, v := range subquery.GetParameters() {
 		this.SetParameter(k, v)
@@ -88,6 +127,8 @@ func (q *Query) All() *Query {
 func (q *Query) Copy(other *Query) {
 	q.table = other.table
 	q.tableAlias = other.tableAlias
+	q.discriminatorCriterias = other.discriminatorCriterias
+	q.includeDeleted = other.includeDeleted
 
 	if other.GetJoins() != nil {
 		q.joins = make([]*Join, len(other.joins))
@@ -110,6 +151,10 @@ func (q *Query) Copy(other *Query) {
 	}
 
 	q.distinct = other.distinct
+	if other.distinctOn != nil {
+		q.distinctOn = make([]Tokener, len(other.distinctOn))
+		copy(q.distinctOn, other.distinctOn)
+	}
 	if other.Columns != nil {
 		q.Columns = make([]Tokener, len(other.Columns))
 		copy(q.Columns, other.Columns)
@@ -127,10 +172,24 @@ func (q *Query) Copy(other *Query) {
 		q.groupBy = make([]int, len(other.groupBy))
 		copy(q.groupBy, other.groupBy)
 	}
+	q.grouping = other.grouping
+	if other.groupingSets != nil {
+		q.groupingSets = make([][]int, len(other.groupingSets))
+		copy(q.groupingSets, other.groupingSets)
+	}
+	if other.having != nil {
+		q.having, _ = other.having.Clone().(*Criteria)
+	}
 
 	q.skip = other.skip
 	q.limit = other.limit
+	if other.keyset != nil {
+		k := *other.keyset
+		k.reset()
+		q.keyset = &k
+	}
 
+	q.onReplica = other.onReplica
 	q.rawSQL = other.rawSQL
 }
 
@@ -151,6 +210,23 @@ func (q *Query) Skip(skip int64) *Query {
 	return q
 }
 
+// OnReplica marks the query to run on a read replica, if the transaction manager has replicas.
+// It has no effect inside a transaction.
+func (q *Query) OnReplica() *Query {
+	q.onReplica = true
+	return q
+}
+
+// queryDba returns the dba of the replica where the query should run, or the dba of the store
+func (q *Query) queryDba() *dbx.SimpleDBA {
+	if noTx, ok := q.db.GetConnection().(*NoTx); ok {
+		if replica := noTx.replica(q.db.GetContext(), q.onReplica); replica != nil {
+			return dbx.NewSimpleDBA(replica)
+		}
+	}
+	return q.dba
+}
+
 func (q *Query) GetLimit() int64 {
 	return q.limit
 }
@@ -190,6 +266,33 @@ func (q *Query) IsDistinct() bool {
 	return q.distinct
 }
 
+// DistinctOn keeps only the first row of each set of rows where the supplied columns are equal.
+// The first row of each set is the first one according to the query orders.
+//
+// It is native in PostgreSQL (where the orders must start with the same columns)
+// and emulated with ROW_NUMBER() for other databases.
+func (q *Query) DistinctOn(columns ...interface{}) *Query {
+	if q.err != nil {
+		return q
+	}
+
+	q.distinctOn = nil
+	for _, column := range columns {
+		token := tokenizeOne(column)
+		q.replaceRaw(token)
+		token.SetTableAlias(q.tableAlias)
+		q.distinctOn = append(q.distinctOn, token)
+	}
+
+	q.rawSQL = nil
+
+	return q
+}
+
+func (q *Query) GetDistinctOn() []Tokener {
+	return q.distinctOn
+}
+
 // COL ===
 
 func (q *Query) ColumnsReset() {
@@ -605,6 +708,8 @@ func (q *Query) GroupByUntil(untilPos int) *Query {
 	for i := 0; i < untilPos; i++ {
 		q.groupBy[i] = i + 1
 	}
+	q.grouping = GROUPING_SIMPLE
+	q.groupingSets = nil
 
 	q.rawSQL = nil
 
@@ -617,6 +722,8 @@ func (q *Query) GroupByPos(pos ...int) *Query {
 	}
 
 	q.groupBy = pos
+	q.grouping = GROUPING_SIMPLE
+	q.groupingSets = nil
 
 	q.rawSQL = nil
 
@@ -646,6 +753,8 @@ func (q *Query) GroupBy(cols ...*Column) *Query {
 	}
 
 	q.rawSQL = nil
+	q.grouping = GROUPING_SIMPLE
+	q.groupingSets = nil
 
 	length := len(cols)
 	if length == 0 {
@@ -683,6 +792,8 @@ func (q *Query) GroupByAs(aliases ...string) *Query {
 	}
 
 	q.rawSQL = nil
+	q.grouping = GROUPING_SIMPLE
+	q.groupingSets = nil
 
 	length := len(aliases)
 	if length == 0 {
@@ -712,6 +823,114 @@ func (q *Query) GroupByAs(aliases ...string) *Query {
 	return q
 }
 
+// GroupByRollup groups by the supplied columns, adding subtotal rows for each level,
+// from right to left, and a grand total row. ex: GROUP BY ROLLUP(a, b)
+//
+// The columns must have been added to the query columns.
+func (q *Query) GroupByRollup(cols ...*Column) *Query {
+	return q.groupByType(GROUPING_ROLLUP, cols)
+}
+
+// GroupByCube groups by the supplied columns, adding subtotal rows
+// for all the combinations of the columns. ex: GROUP BY CUBE(a, b)
+//
+// The columns must have been added to the query columns.
+func (q *Query) GroupByCube(cols ...*Column) *Query {
+	return q.groupByType(GROUPING_CUBE, cols)
+}
+
+func (q *Query) groupByType(grouping GroupingType, cols []*Column) *Query {
+	if q.err != nil {
+		return q
+	}
+
+	positions, err := q.columnPositions(cols)
+	if err != nil {
+		return &Query{err: err}
+	}
+	q.groupBy = positions
+	q.grouping = grouping
+	q.groupingSets = nil
+
+	q.rawSQL = nil
+
+	return q
+}
+
+// GroupingSets groups by each of the supplied sets of columns. An empty set means the grand total.
+// ex: GROUP BY GROUPING SETS ((a, b), (a), ())
+//
+// The columns must have been added to the query columns.
+func (q *Query) GroupingSets(sets ...[]*Column) *Query {
+	if q.err != nil {
+		return q
+	}
+
+	q.groupBy = nil
+	q.groupingSets = make([][]int, len(sets))
+	for k, set := range sets {
+		positions, err := q.columnPositions(set)
+		if err != nil {
+			return &Query{err: err}
+		}
+		q.groupingSets[k] = positions
+		// groupBy holds all the grouped columns
+		for _, pos := range positions {
+			if !containsInt(q.groupBy, pos) {
+				q.groupBy = append(q.groupBy, pos)
+			}
+		}
+	}
+	q.grouping = GROUPING_SETS
+
+	q.rawSQL = nil
+
+	return q
+}
+
+func (q *Query) GetGrouping() GroupingType {
+	return q.grouping
+}
+
+// GetGroupingSetsTokens returns the tokens of each grouping set defined with GroupingSets
+func (q *Query) GetGroupingSetsTokens() [][]Group {
+	sets := make([][]Group, len(q.groupingSets))
+	for k, set := range q.groupingSets {
+		sets[k] = make([]Group, len(set))
+		for i, idx := range set {
+			sets[k][i].Position = idx - 1
+			sets[k][i].Token = q.Columns[idx-1]
+		}
+	}
+	return sets
+}
+
+// columnPositions returns the positions (1 based) of the supplied columns in the query columns
+func (q *Query) columnPositions(cols []*Column) ([]int, error) {
+	positions := make([]int, len(cols))
+	for i, col := range cols {
+		for k, token := range q.Columns {
+			if ch, ok := token.(*ColumnHolder); ok && ch.GetColumn().Equals(col) {
+				positions[i] = k + 1
+				break
+			}
+		}
+		if positions[i] == 0 {
+			return nil, faults.Errorf("column '%s' was not found in the query columns", col)
+		}
+	}
+	return positions, nil
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 // Adds a Having clause to the query.
 // The tokens are not processed. You will have to explicitly set all table alias.
 func (q *Query) Having(having ...*Criteria) *Query {
@@ -834,14 +1053,17 @@ func (q *Query) listIntoClosure(transformer interface{}) ([]interface{}, error)
 		q.All()
 	}
 
-	rsql := q.getCachedSql()
+	rsql, err := q.getCachedSql()
+	if err != nil {
+		return nil, err
+	}
 	q.debugSQL(rsql.OriSql, 2)
 
 	params, err := rsql.BuildValues(q.DmlBase.parameters)
 	if err != nil {
 		return nil, faults.Wrap(err)
 	}
-	r, e := q.DmlBase.dba.QueryIntoX(q.db.GetContext(), rsql.Sql, transformer, params...)
+	r, e := q.queryDba().QueryIntoX(q.db.GetContext(), rsql.Sql, transformer, params...)
 	if e != nil {
 		return nil, e
 	}
@@ -855,14 +1077,17 @@ func (q *Query) listClosure(transformer func(rows *sql.Rows) error) error {
 		q.All()
 	}
 
-	rsql := q.getCachedSql()
+	rsql, err := q.getCachedSql()
+	if err != nil {
+		return err
+	}
 	q.debugSQL(rsql.OriSql, 2)
 
 	params, err := rsql.BuildValues(q.DmlBase.parameters)
 	if err != nil {
 		return faults.Wrap(err)
 	}
-	err = q.DmlBase.dba.QueryClosureX(q.db.GetContext(), rsql.Sql, transformer, params...)
+	err = q.queryDba().QueryClosureX(q.db.GetContext(), rsql.Sql, transformer, params...)
 	return faults.Wrap(err)
 }
 
@@ -874,14 +1099,17 @@ func (q *Query) list(rowMapper dbx.IRowTransformer) (coll.Collection, error) {
 		q.All()
 	}
 
-	rsql := q.getCachedSql()
+	rsql, err := q.getCachedSql()
+	if err != nil {
+		return nil, err
+	}
 	q.debugSQL(rsql.OriSql, 2)
 
 	params, err := rsql.BuildValues(q.DmlBase.parameters)
 	if err != nil {
 		return nil, faults.Wrap(err)
 	}
-	list, err := q.DmlBase.dba.QueryCollectionX(q.db.GetContext(), rsql.Sql, rowMapper, params...)
+	list, err := q.queryDba().QueryCollectionX(q.db.GetContext(), rsql.Sql, rowMapper, params...)
 	if err != nil {
 		return nil, faults.Wrap(err)
 	}
@@ -1115,14 +1343,17 @@ func (q *Query) SelectInto(dest ...interface{}) (bool, error) {
 		q.All()
 	}
 
-	rsql := q.getCachedSql()
+	rsql, err := q.getCachedSql()
+	if err != nil {
+		return false, err
+	}
 	q.debugSQL(rsql.OriSql, 1)
 
 	params, err := rsql.BuildValues(q.DmlBase.parameters)
 	if err != nil {
 		return false, faults.Wrap(err)
 	}
-	found, e := q.dba.QueryRowX(q.db.GetContext(), rsql.Sql, params, dest...)
+	found, e := q.queryDba().QueryRowX(q.db.GetContext(), rsql.Sql, params, dest...)
 	if e != nil {
 		return false, e
 	}
@@ -1253,17 +1484,51 @@ func (q *Query) selectTransformer(rowMapper dbx.IRowTransformer) (interface{}, e
 	return list.Enumerator().Next(), nil // first one
 }
 
+func (q *Query) captureKeyset(row []interface{}) {
+	if q.keyset != nil {
+		q.keyset.capture(row)
+	}
+}
+
+// ToSQL returns the SQL, and its arguments, that would be executed by the query, without executing it.
+func (q *Query) ToSQL() (Statement, error) {
+	if q.err != nil {
+		return Statement{}, q.err
+	}
+
+	// if no columns were added, add all columns of the driving table
+	if len(q.Columns) == 0 {
+		q.All()
+	}
+
+	rsql, err := q.getCachedSql()
+	if err != nil {
+		return Statement{}, err
+	}
+	return q.toStatement(rsql)
+}
+
 // SQL String. It is cached for multiple access
-func (q *Query) getCachedSql() *RawSql {
+func (q *Query) getCachedSql() (*RawSql, error) {
 	if q.rawSQL == nil {
 		// if the discriminator conditions have not yet been processed, apply them now
 		if q.discriminatorCriterias != nil && q.criteria == nil {
 			q.DmlBase.where(nil)
 		}
 
-		sql := q.db.GetTranslator().GetSqlForQuery(q)
-		q.rawSQL = ToRawSql(sql, q.db.GetTranslator())
+		if q.keyset != nil {
+			defer q.keyset.apply(q)()
+		}
+
+		translator := q.db.GetTranslator()
+		if checker, ok := translator.(QueryChecker); ok {
+			if err := checker.CheckQuery(q); err != nil {
+				return nil, faults.Wrap(err)
+			}
+		}
+		sql := translator.GetSqlForQuery(q)
+		q.rawSQL = ToRawSql(sql, translator)
 	}
 
-	return q.rawSQL
+	return q.rawSQL, nil
 }
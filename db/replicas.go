@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BalancingPolicy chooses, by index, one of the size replicas
+type BalancingPolicy func(size int) int
+
+// RoundRobinPolicy uses each replica in turn
+func RoundRobinPolicy() BalancingPolicy {
+	var counter uint64
+	return func(size int) int {
+		return int((atomic.AddUint64(&counter, 1) - 1) % uint64(size))
+	}
+}
+
+// RandomPolicy uses a random replica
+func RandomPolicy() BalancingPolicy {
+	return func(size int) int {
+		return rand.Intn(size)
+	}
+}
+
+// TmWithReplicas routes queries to the read replicas, chosen by the balancing policy.
+// All queries executed with NoTransaction and the queries marked with OnReplica, executed with Store,
+// go to the replicas. Writes and anything inside a transaction go to the primary database.
+// A nil policy is the RoundRobinPolicy.
+func TmWithReplicas(policy BalancingPolicy, replicas ...*sql.DB) func(*TransactionManager) {
+	if policy == nil {
+		policy = RoundRobinPolicy()
+	}
+	return func(t *TransactionManager) {
+		if t.replicas == nil {
+			t.replicas = &replicaSet{}
+		}
+		t.replicas.dbs = replicas
+		t.replicas.policy = policy
+	}
+}
+
+// TmWithStickyPrimary routes the queries to the primary database during the window after a write
+// done with a context returned by StickyPrimaryContext, so that the writes are visible to the reads
+// that follow, even if the replicas are lagging.
+func TmWithStickyPrimary(window time.Duration) func(*TransactionManager) {
+	return func(t *TransactionManager) {
+		if t.replicas == nil {
+			t.replicas = &replicaSet{}
+		}
+		t.replicas.sticky = window
+	}
+}
+
+type replicaSet struct {
+	dbs    []*sql.DB
+	policy BalancingPolicy
+	sticky time.Duration
+}
+
+// replica returns the replica to use for a query, or nil if the query must go to the primary
+func (r *replicaSet) replica(ctx context.Context) *sql.DB {
+	if r == nil || len(r.dbs) == 0 {
+		return nil
+	}
+	if r.sticky > 0 {
+		if last, ok := ctx.Value(stickyKey{}).(*lastWrite); ok && last.within(r.sticky) {
+			return nil
+		}
+	}
+	return r.dbs[r.policy(len(r.dbs))]
+}
+
+type stickyKey struct{}
+
+type lastWrite struct {
+	mu   sync.Mutex
+	when time.Time
+}
+
+func (l *lastWrite) within(window time.Duration) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return !l.when.IsZero() && time.Since(l.when) < window
+}
+
+// StickyPrimaryContext returns a context that records the time of the writes done with it,
+// usually one per request or session, to be used with TmWithStickyPrimary.
+func StickyPrimaryContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, stickyKey{}, &lastWrite{})
+}
+
+// markWrite records the time of a write in the context of the store
+func markWrite(store IDb) {
+	if last, ok := store.GetContext().Value(stickyKey{}).(*lastWrite); ok {
+		last.mu.Lock()
+		last.when = time.Now()
+		last.mu.Unlock()
+	}
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/quintans/faults"
+	"github.com/quintans/goSQL/dbx"
+)
+
+var savepointName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Savepoint creates a savepoint with the supplied name in the current transaction.
+// The work done after the savepoint can be undone with RollbackTo, without aborting the transaction.
+func (d *Db) Savepoint(name string) error {
+	return faults.Wrap(d.execSavepoint(name, d.GetTranslator().GetSqlForSavepoint))
+}
+
+// RollbackTo undoes the work done in the current transaction since the savepoint was created.
+// The savepoint remains valid.
+func (d *Db) RollbackTo(name string) error {
+	return faults.Wrap(d.execSavepoint(name, d.GetTranslator().GetSqlForRollbackTo))
+}
+
+// Release discards the savepoint, keeping the work done since it was created.
+func (d *Db) Release(name string) error {
+	return faults.Wrap(d.execSavepoint(name, d.GetTranslator().GetSqlForRelease))
+}
+
+func (d *Db) execSavepoint(name string, sqlFor func(string) string) error {
+	if _, ok := d.GetConnection().(*MyTx); !ok {
+		return faults.New("savepoints are only available inside a transaction")
+	}
+	if !savepointName.MatchString(name) {
+		return faults.Errorf("invalid savepoint name '%s'", name)
+	}
+
+	sql := sqlFor(name)
+	if sql == "" {
+		return nil
+	}
+	logger.Debugf("SQL: %s", sql)
+	if _, err := d.GetConnection().ExecContext(d.GetContext(), sql); err != nil {
+		return faults.Errorf("executing statement\nSQL: %s: %w", sql, err)
+	}
+	return nil
+}
+
+// nextSavepoint returns a new savepoint name, unique inside the transaction
+func (t *MyTx) nextSavepoint() string {
+	t.savepoints++
+	return "GOSQL_SP_" + strconv.Itoa(t.savepoints)
+}
+
+// nestedTransaction runs the handler inside a savepoint of the transaction of the store.
+// If the handler fails, the work done by the handler is rolled back, but the transaction goes on.
+// If the store is not in a transaction, the handler is simply called.
+func nestedTransaction(store IDb, handler func(db IDb) error) error {
+	tx, ok := store.GetConnection().(*MyTx)
+	if !ok {
+		return handler(store)
+	}
+
+	name := tx.nextSavepoint()
+	if err := store.Savepoint(name); err != nil {
+		return faults.Wrap(err)
+	}
+	// hooks registered by the handler belong to the nested transaction
+	commits, rollbacks := len(tx.onCommit), len(tx.onRollback)
+	logger.Debugf("Nested transaction begin: %s", name)
+	if err := handler(store); err != nil {
+		logger.Debugf("Nested transaction end: ROLLBACK TO %s", name)
+		if rerr := store.RollbackTo(name); rerr != nil {
+			logger.Errorf("failed to rollback to savepoint %s: %v", name, rerr)
+			err = dbx.JoinFails(err, faults.Errorf("failed to rollback to savepoint %s: %w", name, rerr))
+		}
+		if tx.identities != nil {
+			// the entities changed by the handler are stale
+			tx.identities.clear()
+		}
+		hooks := append([]func(){}, tx.onRollback[rollbacks:]...)
+		tx.onCommit = tx.onCommit[:commits]
+		tx.onRollback = tx.onRollback[:rollbacks]
+		for _, hook := range hooks {
+			hook()
+		}
+		return faults.Wrap(err)
+	}
+	logger.Debugf("Nested transaction end: RELEASE %s", name)
+	return faults.Wrap(store.Release(name))
+}
@@ -0,0 +1,186 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/quintans/faults"
+	"github.com/quintans/goSQL/dbx"
+	coll "github.com/quintans/toolkit/collections"
+	"github.com/quintans/toolkit/ext"
+)
+
+var _ error = &DuplicateAliasFail{}
+
+// DuplicateAliasFail is the error of a table declared with the alias of another table of the same schema
+type DuplicateAliasFail struct {
+	Alias string
+	Table string
+}
+
+func (d *DuplicateAliasFail) Error() string {
+	return fmt.Sprintf("The alias '%s' for the table '%s' is already registered in the schema", d.Alias, d.Table)
+}
+
+// Schema is a registry of tables, and their associations, used to find the table mapped to a struct.
+// Each transaction manager has its schema, so that databases with tables with the same alias can be used in one process.
+type Schema struct {
+	tables map[string]*Table
+	// registration order
+	aliases []string
+	// legacy reads and writes the tables in the global Tables, not failing on a duplicate alias,
+	// and keeps the table registered by its previous alias, when changed with As
+	legacy bool
+}
+
+// DefaultSchema holds the tables declared with TABLE, and is the schema of the transaction managers without one.
+// For backward compatibility, its tables are the ones in Tables.
+var DefaultSchema = &Schema{legacy: true}
+
+func NewSchema() *Schema {
+	return &Schema{tables: map[string]*Table{}}
+}
+
+// TABLE declares a table in the schema, with the camel case of the name as alias.
+// If another table has that alias, the table has a *DuplicateAliasFail fault, unless it gets another alias with As.
+func (s *Schema) TABLE(name string) *Table {
+	if name == "" {
+		return &Table{
+			err: faults.New("empty for table name is not allowed"),
+		}
+	}
+	this := new(Table)
+	this.columnsMap = coll.NewLinkedHashMap()
+	this.columns = coll.NewLinkedHashSet()
+	this.keys = coll.NewLinkedHashSet()
+	this.name = name
+	this.Alias = dbx.ToCamelCase(name)
+	this.schema = s
+	if err := s.register(this, this.Alias); err != nil {
+		this.err = err
+	}
+
+	return this
+}
+
+// Add registers the tables declared in another schema, failing with *DuplicateAliasFail if an alias is already registered.
+// The tables become tables of this schema, so a later As renames them in this schema.
+func (s *Schema) Add(tables ...*Table) error {
+	for _, table := range tables {
+		if table.err != nil {
+			return table.err
+		}
+		if err := s.register(table, table.Alias); err != nil {
+			return err
+		}
+		table.schema = s
+	}
+	return nil
+}
+
+func (s *Schema) register(table *Table, alias string) error {
+	if s.legacy {
+		Tables.Put(ext.Str(alias), table)
+		return nil
+	}
+	if t, ok := s.tables[alias]; ok {
+		if t == table {
+			return nil
+		}
+		return &DuplicateAliasFail{Alias: alias, Table: table.GetName()}
+	}
+	s.tables[alias] = table
+	s.aliases = append(s.aliases, alias)
+	return nil
+}
+
+// rename registers the table by the new alias, no longer registering it by the previous one.
+// If the new alias is taken, the table stays registered by the previous one.
+func (s *Schema) rename(table *Table, alias string) error {
+	if err := s.register(table, alias); err != nil {
+		return err
+	}
+	if !s.legacy && s.tables[table.Alias] == table {
+		delete(s.tables, table.Alias)
+		for k, a := range s.aliases {
+			if a == table.Alias {
+				s.aliases = append(s.aliases[:k], s.aliases[k+1:]...)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// Table returns the table registered with the alias, or nil if there is none
+func (s *Schema) Table(alias string) *Table {
+	if s.legacy {
+		if v, ok := Tables.Get(ext.Str(alias)); ok {
+			table, _ := v.(*Table)
+			return table
+		}
+		return nil
+	}
+	return s.tables[alias]
+}
+
+// values returns the registered tables, in registration order
+func (s *Schema) values() []*Table {
+	if s.legacy {
+		tables := []*Table{}
+		for _, v := range Tables.Values() {
+			if table, ok := v.(*Table); ok {
+				tables = append(tables, table)
+			}
+		}
+		return tables
+	}
+	tables := make([]*Table, len(s.aliases))
+	for k, alias := range s.aliases {
+		tables[k] = s.tables[alias]
+	}
+	return tables
+}
+
+// Tables returns the registered tables, sorted by name.
+// When several logical tables are mapped to the same physical table, only the first is returned.
+func (s *Schema) Tables() []*Table {
+	names := map[string]bool{}
+	tables := []*Table{}
+	for _, table := range s.values() {
+		name := strings.ToUpper(table.GetName())
+		if !names[name] {
+			names[name] = true
+			tables = append(tables, table)
+		}
+	}
+	sort.SliceStable(tables, func(i, j int) bool {
+		return tables[i].GetName() < tables[j].GetName()
+	})
+	return tables
+}
+
+// Associations returns the associations declared from the registered tables
+func (s *Schema) Associations() []*Association {
+	associations := []*Association{}
+	for _, table := range s.values() {
+		associations = append(associations, table.GetAssociations()...)
+	}
+	return associations
+}
+
+// SchemaHolder is implemented by the transaction managers and the stores, informing the schema of their tables
+type SchemaHolder interface {
+	Schema() *Schema
+}
+
+// SchemaOf returns the schema of a transaction manager or of a store, or the default schema if they do not have one
+func SchemaOf(v interface{}) *Schema {
+	if h, ok := v.(SchemaHolder); ok {
+		if s := h.Schema(); s != nil {
+			return s
+		}
+	}
+	return DefaultSchema
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaAs(t *testing.T) {
+	schema := NewSchema()
+	book := schema.TABLE("BOOK")
+	label := schema.TABLE("PUBLISHER").As("Label")
+	require.NoError(t, label.Fault())
+	require.Nil(t, schema.Table("Publisher"))
+
+	// a rejected alias keeps the table registered by its previous alias
+	var dup *DuplicateAliasFail
+	require.ErrorAs(t, book.As("Label").Fault(), &dup)
+	require.Equal(t, "Label", dup.Alias)
+	require.Same(t, book, schema.Table("Book"))
+	require.Same(t, label, schema.Table("Label"))
+}
+
+func TestSchemaAdd(t *testing.T) {
+	declared := NewSchema()
+	book := declared.TABLE("BOOK")
+
+	schema := NewSchema()
+	require.NoError(t, schema.Add(book))
+	require.Same(t, schema, book.Schema())
+
+	// the added table is renamed in the schema where it was added
+	require.NoError(t, book.As("Volume").Fault())
+	require.Same(t, book, schema.Table("Volume"))
+	require.Nil(t, schema.Table("Book"))
+
+	var dup *DuplicateAliasFail
+	require.ErrorAs(t, schema.Add(NewSchema().TABLE("VOLUME")), &dup)
+}
@@ -21,6 +21,13 @@ type Table struct {
 	version        *Column         // column version
 	deletion       *Column         // logic deletion column
 	discriminators []Discriminator //
+	indexes        []*Index        // indexes and unique constraints
+	// declared associations, by relation, used to derive foreign keys
+	references    []*Association
+	referenceKeys map[string]bool
+
+	// schema where the table is registered
+	schema *Schema
 
 	PreInsertTrigger func(*Insert)
 	PreUpdateTrigger func(*Update)
@@ -29,20 +36,9 @@ type Table struct {
 	err error
 }
 
+// TABLE declares a table in the default schema
 func TABLE(name string) *Table {
-	if name == "" {
-		return &Table{
-			err: faults.New("empty for table name is not allowed"),
-		}
-	}
-	this := new(Table).As(dbx.ToCamelCase(name))
-	this.columnsMap = coll.NewLinkedHashMap()
-	this.columns = coll.NewLinkedHashSet()
-	this.keys = coll.NewLinkedHashSet()
-	this.name = name
-	AddEntity(this)
-
-	return this
+	return DefaultSchema.TABLE(name)
 }
 
 func (t *Table) As(alias string) *Table {
@@ -51,10 +47,33 @@ func (t *Table) As(alias string) *Table {
 			err: faults.New("empty for table alias is not allowed"),
 		}
 	}
+	if t.schema != nil && alias != t.Alias {
+		if err := t.schema.rename(t, alias); err != nil {
+			t.err = err
+			return t
+		}
+		// the table no longer collides with the one with its previous alias
+		if _, dup := t.err.(*DuplicateAliasFail); dup {
+			t.err = nil
+		}
+	}
 	t.Alias = alias
 	return t
 }
 
+// Fault returns the error of the table declaration, if any
+func (t *Table) Fault() error {
+	return t.err
+}
+
+// Schema returns the schema where the table is registered
+func (t *Table) Schema() *Schema {
+	if t.schema == nil {
+		return DefaultSchema
+	}
+	return t.schema
+}
+
 // gets the table name
 func (t *Table) GetName() string {
 	return t.name
@@ -201,6 +220,19 @@ func (t *Table) GetDeletionColumn() *Column {
 	return t.deletion
 }
 
+// notDeletedCriteria returns the criteria for the rows that are not logically deleted,
+// or nil if the table has no deletion column.
+// A NULL flag is not deleted, since inserts do not set the flag.
+func (t *Table) notDeletedCriteria() *Criteria {
+	if t == nil || t.deletion == nil {
+		return nil
+	}
+	if t.deletion.deleted == nil {
+		return t.deletion.IsNull()
+	}
+	return Or(t.deletion.IsNull(), t.deletion.Matches(t.deletion.notDeleted))
+}
+
 func (t *Table) Equals(obj interface{}) bool {
 	if t == obj {
 		return true
@@ -150,6 +150,12 @@ func Lower(token interface{}) *Token {
 	return NewToken(TOKEN_LOWER, token)
 }
 
+// Grouping returns 1 when the column is aggregated in a super-aggregate row
+// (GROUP BY ROLLUP, CUBE or GROUPING SETS), and 0 otherwise.
+func Grouping(column interface{}) *Token {
+	return NewToken(TOKEN_GROUPING, column)
+}
+
 // pass nil to ignore column
 func Count(column interface{}) *Token {
 	if column == nil {
@@ -1,11 +1,13 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"reflect"
 	"runtime/debug"
-	"strings"
 	"sync"
+	"time"
 
 	"github.com/quintans/faults"
 	"github.com/quintans/goSQL/dbx"
@@ -18,15 +20,55 @@ var (
 
 type MyTx struct {
 	*sql.Tx
+	// driver of the database where the transaction runs
+	driver driver.Driver
+	// number of savepoints created by nested transactions
+	savepoints int
+	// hooks to run after the transaction ends
+	onCommit   []func()
+	onRollback []func()
+	// entities loaded in the transaction. nil if the identity map is not enabled
+	identities *identityMap
+}
+
+// Driver returns the driver of the database where the transaction runs
+func (t *MyTx) Driver() driver.Driver {
+	return t.driver
+}
+
+func (t *MyTx) afterCommit() {
+	for _, hook := range t.onCommit {
+		hook()
+	}
+}
+
+func (t *MyTx) afterRollback() {
+	for _, hook := range t.onRollback {
+		hook()
+	}
 }
 
 type NoTx struct {
 	*sql.DB
+	// replicas for the queries. nil if there are none
+	replicas *replicaSet
+	// allReads routes all queries to the replicas, not only the ones marked with OnReplica
+	allReads bool
+}
+
+// replica returns the replica where the query should run, or nil for the primary
+func (t *NoTx) replica(ctx context.Context, onReplica bool) *sql.DB {
+	if !t.allReads && !onReplica {
+		return nil
+	}
+	return t.replicas.replica(ctx)
 }
 
 type ITransactionManager interface {
 	With(db IDb) ITransactionManager
 	Transaction(handler func(db IDb) error) error
+	TransactionWith(ctx context.Context, opts *sql.TxOptions, handler func(db IDb) error) error
+	TransactionCtx(ctx context.Context, propagation Propagation, handler func(ctx context.Context, db IDb) error) error
 	NoTransaction(handler func(db IDb) error) error
 	Store() IDb
 }
@@ -34,10 +76,20 @@ type ITransactionManager interface {
 var _ ITransactionManager = (*TransactionManager)(nil)
 
 type TransactionManager struct {
-	cache      sync.Map
-	database   *sql.DB
-	translator Translator
-	dbFactory  func(dbx.IConnection, Mapper) IDb
+	cache       sync.Map
+	database    *sql.DB
+	translator  Translator
+	dbFactory   func(dbx.IConnection, Mapper) IDb
+	retry       *retryPolicy
+	replicas    *replicaSet
+	identityMap bool
+	naming      NamingStrategy
+	schema      *Schema
+}
+
+type retryPolicy struct {
+	maxAttempts int
+	backoff     func(attempt int) time.Duration
 }
 
 func TmWithDbFactory(dbFactory func(dbx.IConnection, Mapper) IDb) func(*TransactionManager) {
@@ -46,6 +98,34 @@ func TmWithDbFactory(dbFactory func(dbx.IConnection, Mapper) IDb) func(*Transact
 	}
 }
 
+// TmWithRetry re-runs a transaction, up to maxAttempts times, when it fails with an error
+// that the translator classifies as retryable, like a deadlock or a serialization failure.
+// backoff returns the time to wait before the next attempt, starting at attempt 1. It can be nil.
+func TmWithRetry(maxAttempts int, backoff func(attempt int) time.Duration) func(*TransactionManager) {
+	return func(t *TransactionManager) {
+		t.retry = &retryPolicy{
+			maxAttempts: maxAttempts,
+			backoff:     backoff,
+		}
+	}
+}
+
+// TmWithNamingStrategy sets how struct fields are matched with the column aliases, when not set with `sql:"col=ALIAS"`.
+// By default, a field matches the alias equal to its capitalized name.
+func TmWithNamingStrategy(naming NamingStrategy) func(*TransactionManager) {
+	return func(t *TransactionManager) {
+		t.naming = naming
+	}
+}
+
+// TmWithSchema sets the schema with the tables used by the transaction manager, instead of the default schema.
+// The schema decides the table mapped to a struct, so several databases can be used in one process.
+func TmWithSchema(schema *Schema) func(*TransactionManager) {
+	return func(t *TransactionManager) {
+		t.schema = schema
+	}
+}
+
 // NewTransactionManager creates a new Transaction Manager
 func NewTransactionManager(database *sql.DB, translator Translator, options ...func(*TransactionManager)) *TransactionManager {
 	t := &TransactionManager{
@@ -54,6 +134,8 @@ func NewTransactionManager(database *sql.DB, translator Translator, options ...f
 		dbFactory: func(c dbx.IConnection, cache Mapper) IDb {
 			return NewDb(c, translator, cache)
 		},
+		naming: DefaultNaming,
+		schema: DefaultSchema,
 	}
 
 	for _, o := range options {
@@ -62,6 +144,11 @@ func NewTransactionManager(database *sql.DB, translator Translator, options ...f
 	return t
 }
 
+// Schema returns the schema with the tables used by the transaction manager
+func (t *TransactionManager) Schema() *Schema {
+	return t.schema
+}
+
 func (d *TransactionManager) RegisterType(v interface{}) error {
 	_, err := d.Mappings(reflect.TypeOf(v))
 	return faults.Wrap(err)
@@ -76,17 +163,24 @@ func (t *TransactionManager) Mappings(typ reflect.Type) (map[string]*StructPrope
 		// create an attribute data structure as a map of types keyed by a string.
 		attrs := map[string]*StructProperty{}
 
-		err := t.walkTreeStruct(typ, attrs, nil)
+		fallbacks := map[string]*StructProperty{}
+		err := t.walkTreeStruct(typ, attrs, fallbacks, nil)
 		if err != nil {
 			return nil, faults.Wrap(err)
 		}
+		// the capitalized field names still match, if not taken, so that associations are found
+		for name, ep := range fallbacks {
+			if _, ok := attrs[name]; !ok {
+				attrs[name] = ep
+			}
+		}
 
 		elem, _ = t.cache.LoadOrStore(typ, attrs)
 	}
 	return elem.(map[string]*StructProperty), nil
 }
 
-func (t *TransactionManager) walkTreeStruct(typ reflect.Type, attrs map[string]*StructProperty, index []int) error {
+func (t *TransactionManager) walkTreeStruct(typ reflect.Type, attrs, fallbacks map[string]*StructProperty, index []int) error {
 	// if a pointer to a struct is passed, get the type of the dereferenced object
 	if typ.Kind() == reflect.Ptr {
 		typ = typ.Elem()
@@ -102,44 +196,31 @@ func (t *TransactionManager) walkTreeStruct(typ reflect.Type, attrs map[string]*
 	num := typ.NumField()
 	for i := 0; i < num; i++ {
 		p := typ.Field(i)
-		var omit, embedded bool
+		tag := ParseFieldTag(p.Tag.Get(sqlKey))
+		omit, embedded := tag.Omit, tag.Embedded
 		var converter Converter
-		sqlVal := p.Tag.Get(sqlKey)
-		if sqlVal != "" {
-			splits := strings.Split(sqlVal, ",")
-			for _, s := range splits {
-				v := strings.TrimSpace(s)
-				switch v {
-				case sqlOmissionVal:
-					omit = true
-				case sqlEmbededVal, sqlEmbeddedVal:
-					embedded = true
-				default:
-					if strings.HasPrefix(v, converterTag) {
-						cn := v[len(converterTag):]
-						converter = t.translator.GetConverter(cn)
-						if converter == nil {
-							return faults.Errorf("Converter %s is not registered", cn)
-						}
-					}
-				}
+		if tag.Converter != "" {
+			converter = t.translator.GetConverter(tag.Converter)
+			if converter == nil {
+				return faults.Errorf("Converter %s is not registered", tag.Converter)
 			}
 		}
 		x := append(index, i)
 		if p.Anonymous {
-			if err := t.walkTreeStruct(p.Type, attrs, x); err != nil {
+			if err := t.walkTreeStruct(p.Type, attrs, fallbacks, x); err != nil {
 				return faults.Wrap(err)
 			}
 		} else if embedded {
-			if err := t.walkTreeStruct(p.Type, attrs, x); err != nil {
+			if err := t.walkTreeStruct(p.Type, attrs, fallbacks, x); err != nil {
 				return faults.Wrap(err)
 			}
 		} else {
 			ep := &StructProperty{}
+			ep.field = p.Name
 			ep.getter = makeGetter(x)
 			ep.setter = makeSetter(x)
-			capitalized := strings.ToUpper(p.Name[:1]) + p.Name[1:]
-			attrs[capitalized] = ep
+			attrs[fieldAlias(t.naming, p.Name, tag)] = ep
+			fallbacks[capFirst(p.Name)] = ep
 			ep.Omit = omit
 			ep.converter = converter
 			// we want pointers. only pointer are addressable
@@ -164,12 +245,66 @@ func (t *TransactionManager) With(db IDb) ITransactionManager {
 	return HollowTransactionManager{db}
 }
 
+// Transaction runs the handler inside a new transaction.
+// It does not know about any enclosing transaction, so to nest a transaction inside a handler,
+// creating a savepoint, use With(store).Transaction or TransactionWith with the context of the store.
 func (t *TransactionManager) Transaction(handler func(db IDb) error) error {
+	return t.TransactionWith(context.Background(), nil, handler)
+}
+
+// TransactionWith runs the handler inside a transaction started with the supplied context and options,
+// like the isolation level or read only.
+// The context is bound to the store passed to the handler,
+// so cancelling the context also aborts the transaction.
+//
+// If the context is the one of a store in a transaction, like store.GetContext() inside a handler,
+// the handler runs nested in that transaction, in a savepoint, and the options are ignored.
+//
+// ex:
+//   tm.TransactionWith(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable}, func(store db.IDb) error {...})
+func (t *TransactionManager) TransactionWith(ctx context.Context, opts *sql.TxOptions, handler func(db IDb) error) error {
+	if outer := contextStore(ctx); outer != nil {
+		return nestedTransaction(outer, handler)
+	}
+	return t.newTransaction(ctx, opts, handler)
+}
+
+// newTransaction starts a new transaction, retrying it if configured with TmWithRetry
+func (t *TransactionManager) newTransaction(ctx context.Context, opts *sql.TxOptions, handler func(db IDb) error) error {
+	if t.retry == nil {
+		return t.transaction(ctx, opts, handler)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := t.transaction(ctx, opts, handler)
+		if err == nil || attempt >= t.retry.maxAttempts || !t.translator.IsRetryable(err) {
+			return err
+		}
+
+		logger.Warnf("Transaction attempt %d failed with a retryable error: %v", attempt, err)
+		if t.retry.backoff != nil {
+			select {
+			case <-ctx.Done():
+				return faults.Wrap(err)
+			case <-time.After(t.retry.backoff(attempt)):
+			}
+		}
+	}
+}
+
+func (t *TransactionManager) transaction(ctx context.Context, opts *sql.TxOptions, handler func(db IDb) error) error {
 	logger.Debugf("Transaction begin")
-	tx, err := t.database.Begin()
+	tx, err := t.database.BeginTx(ctx, opts)
 	if err != nil {
 		return faults.Wrap(err)
 	}
+	myTx := new(MyTx)
+	myTx.Tx = tx
+	myTx.driver = t.database.Driver()
+	if t.identityMap {
+		myTx.identities = newIdentityMap()
+	}
+
 	defer func() {
 		err := recover()
 		if err != nil {
@@ -178,29 +313,34 @@ func (t *TransactionManager) Transaction(handler func(db IDb) error) error {
 			if rerr != nil {
 				logger.Errorf("failed to rollback: %v", rerr)
 			}
+			myTx.afterRollback()
 			panic(err) // up you go
 		}
 	}()
 
-	myTx := new(MyTx)
-	myTx.Tx = tx
-
 	inTx := new(bool)
 	*inTx = true
-	err = handler(t.dbFactory(myTx, t))
+	// the context holds the store, so that the transaction is found by the transactions started with it
+	_, store := withStore(ctx, t.dbFactory(myTx, t))
+	err = handler(store)
 	*inTx = false
 	if err == nil {
 		logger.Debug("Transaction end: COMMIT")
 		cerr := tx.Commit()
 		if cerr != nil {
 			logger.Errorf("failed to commit: %v", cerr)
+			myTx.afterRollback()
+			return faults.Errorf("failed to commit: %w", cerr)
 		}
+		myTx.afterCommit()
 	} else {
 		logger.Debug("Transaction end: ROLLBACK")
 		rerr := tx.Rollback()
 		if rerr != nil {
 			logger.Errorf("failed to rollback: %v", rerr)
+			err = dbx.JoinFails(err, faults.Errorf("failed to rollback: %w", rerr))
 		}
+		myTx.afterRollback()
 	}
 	return faults.Wrap(err)
 }
@@ -217,6 +357,8 @@ func (t *TransactionManager) NoTransaction(handler func(db IDb) error) error {
 
 	myTx := new(NoTx)
 	myTx.DB = t.database
+	myTx.replicas = t.replicas
+	myTx.allReads = true
 
 	inTx := new(bool)
 	*inTx = true
@@ -233,8 +375,13 @@ func (this TransactionManager) WithoutTransaction(handler func(db IDb) error) er
 }
 */
 
+// Store returns a store, outside of a transaction, whose connection is the primary *sql.DB.
+// With replicas, the connection is a *NoTx, so that the queries marked with OnReplica go to the replicas.
 func (t *TransactionManager) Store() IDb {
-	return t.dbFactory(t.database, t)
+	if t.replicas == nil || len(t.replicas.dbs) == 0 {
+		return t.dbFactory(t.database, t)
+	}
+	return t.dbFactory(&NoTx{DB: t.database, replicas: t.replicas}, t)
 }
 
 var _ ITransactionManager = HollowTransactionManager{}
@@ -247,8 +394,22 @@ func (t HollowTransactionManager) With(db IDb) ITransactionManager {
 	return HollowTransactionManager{db}
 }
 
+// Transaction runs the handler in the store of the enclosing transaction.
+// If the store is in a transaction, a savepoint is created, so that a failure of the handler
+// only rolls back the work done by the handler, without aborting the enclosing transaction.
 func (t HollowTransactionManager) Transaction(handler func(db IDb) error) error {
-	return handler(t.db)
+	return nestedTransaction(t.db, handler)
+}
+
+// TransactionWith behaves like Transaction, binding the context to the store while the handler runs.
+// The options are ignored, since the isolation level and the read only mode are the ones of the enclosing transaction.
+func (t HollowTransactionManager) TransactionWith(ctx context.Context, opts *sql.TxOptions, handler func(db IDb) error) error {
+	if ctx != nil {
+		previous := t.db.GetContext()
+		t.db.WithContext(ctx)
+		defer t.db.WithContext(previous)
+	}
+	return nestedTransaction(t.db, handler)
 }
 
 func (t HollowTransactionManager) NoTransaction(handler func(db IDb) error) error {
@@ -258,3 +419,21 @@ func (t HollowTransactionManager) NoTransaction(handler func(db IDb) error) erro
 func (t HollowTransactionManager) Store() IDb {
 	return t.db
 }
+
+func (t HollowTransactionManager) Schema() *Schema {
+	return SchemaOf(t.db)
+}
+
+// fieldAlias returns the column alias matched by the field.
+// The col tag is the alias as is, like ID or URL, while the column tag is the column name.
+func fieldAlias(naming NamingStrategy, field string, tag FieldTag) string {
+	switch {
+	case tag.Col != "":
+		return tag.Col
+	case tag.Column != "":
+		// the default alias of the column
+		return dbx.ToCamelCase(tag.Column)
+	default:
+		return naming.FieldAlias(field)
+	}
+}
@@ -1,8 +1,6 @@
 package db
 
-//import (
-//	tk "github.com/quintans/toolkit"
-//)
+import "fmt"
 
 type DmlType int
 
@@ -31,6 +29,10 @@ type Translator interface {
 	GetSqlForUpdate(update *Update) string
 	// DELTE
 	GetSqlForDelete(del *Delete) string
+	// SAVEPOINTS. An empty statement means that there is nothing to execute
+	GetSqlForSavepoint(name string) string
+	GetSqlForRollbackTo(name string) string
+	GetSqlForRelease(name string) string
 	// GetSqlForSequence(sequence *Sequence, nextValue bool) string
 	GetAutoNumberQuery(column *Column) string
 	//	GetMaxTableChars() int
@@ -42,4 +44,36 @@ type Translator interface {
 	IgnoreNullKeys() bool
 	RegisterConverter(name string, c Converter)
 	GetConverter(name string) Converter
+	// IsRetryable reports if the error, like a deadlock or a serialization failure,
+	// can be solved by running the transaction again
+	IsRetryable(err error) bool
+	// BulkLoad loads the rows supplied by the reader into the columns of the table
+	BulkLoad(store IDb, table *Table, columns []*Column, reader ValuesReader) (int64, error)
+	// BindValue returns the value to bind to a parameter of a column with a declared type
+	BindValue(column *Column, value interface{}) (interface{}, error)
+	// GetSqlForCreate returns the DDL statements that create the tables, with their keys, sequences,
+	// indexes and foreign keys
+	GetSqlForCreate(tables []*Table) []string
+	// GetSqlForDrop returns the DDL statements that drop what GetSqlForCreate creates
+	GetSqlForDrop(tables []*Table) []string
+	// ReadCatalog reads, from the database catalog, the tables of the current schema
+	ReadCatalog(store IDb) ([]*CatalogTable, error)
+}
+
+// QueryChecker is implemented by the translators whose dialect does not support all the features of a query.
+// CheckQuery is called before the query is translated, returning *NotSupportedFail for what can not be translated.
+type QueryChecker interface {
+	CheckQuery(query *Query) error
+}
+
+var _ error = &NotSupportedFail{}
+
+// NotSupportedFail is returned, before executing a query, when it uses a feature not supported by the database dialect
+type NotSupportedFail struct {
+	Feature string
+	Dialect string
+}
+
+func (n *NotSupportedFail) Error() string {
+	return fmt.Sprintf("%s is not supported by the %s dialect", n.Feature, n.Dialect)
 }
@@ -0,0 +1,22 @@
+package db
+
+// OnCommit registers a function to be called after the current transaction is successfully committed,
+// like invalidating a cache or publishing a message.
+// Outside a transaction, the function is called immediately.
+func (d *Db) OnCommit(hook func()) {
+	tx, ok := d.GetConnection().(*MyTx)
+	if !ok {
+		hook()
+		return
+	}
+	tx.onCommit = append(tx.onCommit, hook)
+}
+
+// OnRollback registers a function to be called after the current transaction is rolled back,
+// including when the commit fails.
+// Outside a transaction, the function is never called.
+func (d *Db) OnRollback(hook func()) {
+	if tx, ok := d.GetConnection().(*MyTx); ok {
+		tx.onRollback = append(tx.onRollback, hook)
+	}
+}
@@ -52,6 +52,26 @@ func (u *Update) Values(vals ...interface{}) *Update {
 // Updates all the columns of the table to matching struct fields.
 // Returns the number of affected rows
 func (u *Update) Submit(instance interface{}) (int64, error) {
+	return u.submit(instance, nil)
+}
+
+// SubmitAll updates every struct of the slice, like Submit, preparing the statement only once.
+// Returns the total number of affected rows.
+//
+// An optimistic lock failure does not stop the remaining updates
+// and is reported, by the element index, in a *dbx.BatchFail.
+//
+// The values set before the call are used for every element.
+func (u *Update) SubmitAll(slice interface{}) (int64, error) {
+	kept := u.keepValues()
+	return submitAll(u.GetDb(), slice, func(instance interface{}, stmts *statements) (int64, error) {
+		// the columns to update may differ between elements (omit and marked fields)
+		u.restoreValues(kept)
+		return u.submit(instance, stmts)
+	})
+}
+
+func (u *Update) submit(instance interface{}, stmts *statements) (int64, error) {
 	var invalid bool
 	typ := reflect.TypeOf(instance)
 	if typ.Kind() == reflect.Ptr {
@@ -68,7 +88,8 @@ func (u *Update) Submit(instance interface{}) (int64, error) {
 	}
 
 	var mappings map[string]*EntityProperty
-	var criterias []*Criteria
+	// the criteria are built for every struct, since the version may be checked for some and not for others
+	criterias := make([]*Criteria, 0)
 
 	if typ == u.lastType {
 		mappings = u.lastMappings
@@ -78,8 +99,6 @@ func (u *Update) Submit(instance interface{}) (int64, error) {
 		if err != nil {
 			return 0, faults.Wrap(err)
 		}
-		criterias = make([]*Criteria, 0)
-		u.criteria = nil
 		u.lastMappings = mappings
 		u.lastType = typ
 	}
@@ -120,9 +139,7 @@ func (u *Update) Submit(instance interface{}) (int64, error) {
 				}
 				id = val.Interface()
 
-				if criterias != nil {
-					criterias = append(criterias, column.Matches(Param(alias)))
-				}
+				criterias = append(criterias, column.Matches(Param(alias)))
 				u.SetParameter(alias, id)
 			} else if column.IsVersion() {
 				if !val.IsValid() || (val.Kind() == reflect.Ptr && val.IsNil()) {
@@ -136,16 +153,15 @@ func (u *Update) Submit(instance interface{}) (int64, error) {
 				// if version is 0 it means an update where optimistic locking is ignored
 				if ver != 0 {
 					alias_old := alias + "_old"
-					if criterias != nil {
-						criterias = append(criterias, column.Matches(Param(alias_old)))
-					}
+					criterias = append(criterias, column.Matches(Param(alias_old)))
 					u.SetParameter(alias_old, ver)
 					// increments the version
 					u.Set(column, ver+1)
 					verColumn = column
 				}
 			} else {
-				marked := useMarks && marks[column.GetAlias()]
+				// the marks are the field names, that may differ from the column alias
+				marked := useMarks && marks[bp.field]
 				if val.IsValid() && (!useMarks || marked) {
 					var isNil bool
 					if val.Kind() == reflect.Ptr {
@@ -189,10 +205,7 @@ func (u *Update) Submit(instance interface{}) (int64, error) {
 			}
 		}
 	}
-	if criterias != nil {
-		u.Where(criterias...)
-		u.rawSQL = nil
-	}
+	u.whereKeys(criterias, u.shape(typ, verColumn != nil), stmts)
 
 	// pre trigger
 	if t, isT := instance.(PreUpdater); isT {
@@ -202,7 +215,7 @@ func (u *Update) Submit(instance interface{}) (int64, error) {
 		}
 	}
 
-	affectedRows, err := u.Execute()
+	affectedRows, err := u.execute(stmts)
 	if err != nil {
 		return 0, faults.Wrap(err)
 	}
@@ -232,18 +245,36 @@ func (u *Update) Submit(instance interface{}) (int64, error) {
 
 // returns the number of affected rows
 func (u *Update) Execute() (int64, error) {
+	if identities := identityMapOf(u.db); identities != nil {
+		// it is not possible to know which entities were changed
+		identities.clear()
+	}
+	return u.execute(nil)
+}
+
+func (u *Update) execute(stmts *statements) (int64, error) {
 	table := u.GetTable()
 	if table.PreUpdateTrigger != nil {
 		table.PreUpdateTrigger(u)
 	}
+	if err := u.validate(false); err != nil {
+		return 0, err
+	}
 
 	rsql := u.getCachedSql()
-	u.debugSQL(rsql.OriSql, 1)
+	u.debugSQL(rsql.OriSql, 2)
+	if stmts != nil {
+		stmts.sqls[u.lastShape] = rsql
+	}
 
 	params, err := rsql.BuildValues(u.DmlBase.parameters)
 	if err != nil {
 		return 0, faults.Wrap(err)
 	}
+	markWrite(u.db)
+	if stmts != nil {
+		return stmts.exec(rsql.Sql, params)
+	}
 	affectedRows, e := u.DmlBase.dba.UpdateX(u.db.GetContext(), rsql.Sql, params...)
 	if e != nil {
 		return 0, e
@@ -252,6 +283,12 @@ func (u *Update) Execute() (int64, error) {
 	return affectedRows, nil
 }
 
+// ToSQL returns the SQL, and its arguments, that would be executed by the update, without executing it.
+// The values being set are validated as in Execute.
+func (u *Update) ToSQL() (Statement, error) {
+	return u.validStatement(false, u.getCachedSql)
+}
+
 func (u *Update) getCachedSql() *RawSql {
 	if u.rawSQL == nil {
 		// if the discriminator conditions have not yet been processed, apply them now
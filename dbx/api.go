@@ -13,6 +13,11 @@ type IConnection interface {
 	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
 }
 
+// IPreparer is implemented by the connections that can prepare statements, like *sql.DB and *sql.Tx
+type IPreparer interface {
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
 type IRowTransformer interface {
 	// Initializes the collection that will hold the results
 	// return Creates a Collection
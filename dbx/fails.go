@@ -1,6 +1,13 @@
 package dbx
 
-import tk "github.com/quintans/toolkit"
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	tk "github.com/quintans/toolkit"
+)
 
 const FAULT_PARSE_STATEMENT = "sql-parse"
 const FAULT_VALUES_STATEMENT = "sql-values"
@@ -33,3 +40,100 @@ func NewOptimisticLockFail(message string) *OptimisticLockFail {
 	fail.Fail.Message = message
 	return fail
 }
+
+var _ error = &BatchFail{}
+
+// BatchFail holds the failures of the elements of a batch, by the element index
+type BatchFail struct {
+	Fails map[int]error
+}
+
+func NewBatchFail() *BatchFail {
+	return &BatchFail{Fails: map[int]error{}}
+}
+
+func (b *BatchFail) Error() string {
+	indexes := make([]int, 0, len(b.Fails))
+	for k := range b.Fails {
+		indexes = append(indexes, k)
+	}
+	sort.Ints(indexes)
+	msgs := make([]string, len(indexes))
+	for i, k := range indexes {
+		msgs[i] = fmt.Sprintf("[%d] %s", k, b.Fails[k])
+	}
+	return "batch failed for elements: " + strings.Join(msgs, "; ")
+}
+
+// multi returns the failures, by element index
+func (b *BatchFail) multi() *MultiFail {
+	indexes := make([]int, 0, len(b.Fails))
+	for k := range b.Fails {
+		indexes = append(indexes, k)
+	}
+	sort.Ints(indexes)
+	errs := make([]error, len(indexes))
+	for i, k := range indexes {
+		errs[i] = b.Fails[k]
+	}
+	return &MultiFail{Fails: errs}
+}
+
+// Is allows errors.Is to match any of the failures
+func (b *BatchFail) Is(target error) bool {
+	return b.multi().Is(target)
+}
+
+// As allows errors.As to match any of the failures, by element index
+func (b *BatchFail) As(target interface{}) bool {
+	return b.multi().As(target)
+}
+
+var _ error = &MultiFail{}
+
+// MultiFail holds several errors, like the error of a handler and the error of the rollback
+type MultiFail struct {
+	Fails []error
+}
+
+// JoinFails returns a *MultiFail with the errors that are not nil, or nil if all of them are nil
+func JoinFails(errs ...error) error {
+	m := &MultiFail{}
+	for _, err := range errs {
+		if err != nil {
+			m.Fails = append(m.Fails, err)
+		}
+	}
+	if len(m.Fails) == 0 {
+		return nil
+	}
+	return m
+}
+
+func (m *MultiFail) Error() string {
+	msgs := make([]string, len(m.Fails))
+	for k, err := range m.Fails {
+		msgs[k] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Is allows errors.Is to match any of the errors
+func (m *MultiFail) Is(target error) bool {
+	for _, err := range m.Fails {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As allows errors.As to match any of the errors, the first having precedence
+func (m *MultiFail) As(target interface{}) bool {
+	for _, err := range m.Fails {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/quintans/faults"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJoinFails(t *testing.T) {
+	require.NoError(t, JoinFails(nil, nil))
+
+	lock := NewOptimisticLockFail("stale")
+	err := faults.Wrap(JoinFails(lock, faults.Errorf("failed to rollback: %w", context.Canceled)))
+	require.ErrorIs(t, err, lock)
+	require.ErrorIs(t, err, context.Canceled)
+	require.False(t, errors.Is(err, context.DeadlineExceeded))
+
+	var fail *OptimisticLockFail
+	require.ErrorAs(t, err, &fail)
+	require.Same(t, lock, fail)
+}
+
+func TestBatchFail(t *testing.T) {
+	first := NewOptimisticLockFail("first")
+	second := NewOptimisticLockFail("second")
+	batch := NewBatchFail()
+	batch.Fails[3] = second
+	batch.Fails[1] = first
+
+	err := faults.Wrap(batch)
+	require.ErrorIs(t, err, second)
+	require.False(t, errors.Is(err, context.Canceled))
+
+	// the failure of the lowest index has precedence
+	var fail *OptimisticLockFail
+	require.ErrorAs(t, err, &fail)
+	require.Same(t, first, fail)
+	require.Equal(t, "batch failed for elements: [1] [optimistic-lock] first; [3] [optimistic-lock] second", batch.Error())
+}
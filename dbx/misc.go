@@ -1,9 +1,33 @@
 package dbx
 
 import (
+	"strings"
 	"unicode"
 )
 
+// converts UserID -> USER_ID and HTTPServer -> HTTP_SERVER, keeping acronyms together
+func ToSnakeCase(name string) string {
+	runes := []rune(name)
+	str := ""
+	for k, r := range runes {
+		if r == '_' {
+			if !strings.HasSuffix(str, "_") && str != "" {
+				str += "_"
+			}
+			continue
+		}
+		if k > 0 && unicode.IsUpper(r) && !strings.HasSuffix(str, "_") {
+			prev := runes[k-1]
+			nextLower := k+1 < len(runes) && unicode.IsLower(runes[k+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				str += "_"
+			}
+		}
+		str += string(unicode.ToUpper(r))
+	}
+	return str
+}
+
 // converts helloWorld -> HELLO_WORLD
 func FromCamelCase(name string) string {
 	str := ""
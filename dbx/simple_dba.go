@@ -152,6 +152,20 @@ func (s *SimpleDBA) QueryClosureX(
 	return nil
 }
 
+// QueryRowsX executes the query and returns the rows, for them to be consumed one at a time.
+// The caller is responsible for closing the rows.
+func (s *SimpleDBA) QueryRowsX(
+	ctx context.Context,
+	query string,
+	params ...interface{},
+) (*sql.Rows, error) {
+	rows, err := s.connection.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, rethrow(err, "executing query rows", query, params...)
+	}
+	return rows, nil
+}
+
 //List using the closure arguments.
 //A function is used to build the result list.
 //The types for scanning are supplied by the function arguments. Arguments can be pointers or not.
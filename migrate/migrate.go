@@ -0,0 +1,356 @@
+package migrate
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/quintans/faults"
+	"github.com/quintans/goSQL/db"
+	"github.com/quintans/toolkit/log"
+)
+
+var logger = log.LoggerFor("github.com/quintans/goSQL/migrate")
+
+const (
+	HISTORY_TABLE = "GOSQL_SCHEMA_HISTORY"
+	LOCK_TABLE    = "GOSQL_SCHEMA_LOCK"
+)
+
+// Migration is a versioned change of the schema.
+// Migrations are applied in the order of their version.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(store db.IDb) error
+	// Down undoes the work of Up. It is optional.
+	Down func(store db.IDb) error
+	// Checksum of the SQL of Up. It is empty for migrations written in Go, and these are not verified.
+	Checksum string
+}
+
+// Record is an applied migration, as registered in the history table
+type Record struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+var _ error = &ChecksumFail{}
+
+// ChecksumFail is returned when an applied migration was changed after being applied
+type ChecksumFail struct {
+	Version int64
+	Name    string
+	// checksum registered in the history table
+	Applied string
+	// checksum of the current migration
+	Current string
+}
+
+func (c *ChecksumFail) Error() string {
+	return fmt.Sprintf("migration %d %s was changed after being applied: checksum %s, was %s", c.Version, c.Name, c.Current, c.Applied)
+}
+
+type Migrator struct {
+	tm           db.ITransactionManager
+	historyName  string
+	lockName     string
+	lockTimeout  time.Duration
+	lockInterval time.Duration
+	migrations   map[int64]*Migration
+
+	history *historyTable
+	lock    *lockTable
+
+	err error
+}
+
+// WithHistoryTable sets the name of the table where the applied migrations are registered
+func WithHistoryTable(name string) func(*Migrator) {
+	return func(m *Migrator) {
+		m.historyName = name
+	}
+}
+
+// WithLockTable sets the name of the table used to prevent concurrent migrations
+func WithLockTable(name string) func(*Migrator) {
+	return func(m *Migrator) {
+		m.lockName = name
+	}
+}
+
+// WithLockTimeout sets how long to wait for a lock held by another instance. The default is one minute.
+func WithLockTimeout(timeout time.Duration) func(*Migrator) {
+	return func(m *Migrator) {
+		m.lockTimeout = timeout
+	}
+}
+
+func NewMigrator(tm db.ITransactionManager, options ...func(*Migrator)) *Migrator {
+	m := &Migrator{
+		tm:           tm,
+		historyName:  HISTORY_TABLE,
+		lockName:     LOCK_TABLE,
+		lockTimeout:  time.Minute,
+		lockInterval: time.Second,
+		migrations:   map[int64]*Migration{},
+	}
+	for _, option := range options {
+		option(m)
+	}
+	m.history = newHistoryTable(m.historyName)
+	m.lock = newLockTable(m.lockName)
+	return m
+}
+
+// Add registers a migration written in Go
+func (m *Migrator) Add(version int64, name string, up func(store db.IDb) error, down func(store db.IDb) error) *Migrator {
+	return m.AddMigration(&Migration{
+		Version: version,
+		Name:    name,
+		Up:      up,
+		Down:    down,
+	})
+}
+
+func (m *Migrator) AddMigration(migration *Migration) *Migrator {
+	if m.err != nil {
+		return m
+	}
+
+	if migration.Version <= 0 {
+		m.err = faults.Errorf("invalid version %d for migration %s: versions must be positive", migration.Version, migration.Name)
+		return m
+	}
+	if migration.Up == nil {
+		m.err = faults.Errorf("migration %d %s without Up", migration.Version, migration.Name)
+		return m
+	}
+	if other, ok := m.migrations[migration.Version]; ok {
+		m.err = faults.Errorf("migrations %s and %s have the same version %d", other.Name, migration.Name, migration.Version)
+		return m
+	}
+	m.migrations[migration.Version] = migration
+	return m
+}
+
+// Migrations returns the registered migrations, ordered by version
+func (m *Migrator) Migrations() []*Migration {
+	list := make([]*Migration, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		list = append(list, migration)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Version < list[j].Version
+	})
+	return list
+}
+
+// History returns the applied migrations, ordered by version
+func (m *Migrator) History() ([]*Record, error) {
+	if err := m.ensureTables(); err != nil {
+		return nil, err
+	}
+	return m.history.list(m.tm.Store())
+}
+
+// Pending returns the registered migrations that were not yet applied
+func (m *Migrator) Pending() ([]*Migration, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	records, err := m.History()
+	if err != nil {
+		return nil, err
+	}
+	return m.pending(records, 0)
+}
+
+// Up applies all the pending migrations
+func (m *Migrator) Up() error {
+	return m.UpTo(0)
+}
+
+// UpTo applies the pending migrations up to, and including, the version.
+// A zero version applies all the pending migrations.
+//
+// Before applying, the applied migrations are checked against the registered ones.
+// Each migration runs, and is registered in the history table, in its own transaction.
+// Keep in mind that some databases, like MySQL and Oracle, commit DDL statements immediately.
+func (m *Migrator) UpTo(version int64) error {
+	return m.locked(func(records []*Record) error {
+		pending, err := m.pending(records, version)
+		if err != nil {
+			return err
+		}
+		for _, migration := range pending {
+			if err := m.apply(migration); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Down undoes the last applied migration
+func (m *Migrator) Down() error {
+	return m.locked(func(records []*Record) error {
+		if len(records) == 0 {
+			return nil
+		}
+		return m.revert(records[len(records)-1])
+	})
+}
+
+// DownTo undoes the applied migrations with a version greater than the version, from the last to the first.
+// A zero version undoes all the applied migrations.
+func (m *Migrator) DownTo(version int64) error {
+	return m.locked(func(records []*Record) error {
+		for k := len(records) - 1; k >= 0 && records[k].Version > version; k-- {
+			if err := m.revert(records[k]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Unlock releases the lock, even if it is held by another instance.
+// It is meant to clear the lock left behind by an instance that died while migrating.
+func (m *Migrator) Unlock() error {
+	if err := m.ensureTables(); err != nil {
+		return err
+	}
+	return m.lock.release(m.tm.Store())
+}
+
+// locked calls the handler with the applied migrations, already verified, while holding the lock
+func (m *Migrator) locked(handler func(records []*Record) error) error {
+	if m.err != nil {
+		return m.err
+	}
+	if err := m.ensureTables(); err != nil {
+		return err
+	}
+
+	store := m.tm.Store()
+	if err := m.lock.acquire(store, m.lockTimeout, m.lockInterval); err != nil {
+		return err
+	}
+	defer func() {
+		if err := m.lock.release(store); err != nil {
+			logger.Errorf("failed to release the migration lock: %+v", err)
+		}
+	}()
+
+	records, err := m.history.list(store)
+	if err != nil {
+		return err
+	}
+	if err := m.verify(records); err != nil {
+		return err
+	}
+	return handler(records)
+}
+
+// verify checks that every applied migration is registered and was not changed
+func (m *Migrator) verify(records []*Record) error {
+	for _, record := range records {
+		migration, ok := m.migrations[record.Version]
+		if !ok {
+			return faults.Errorf("applied migration %d %s is not registered", record.Version, record.Name)
+		}
+		if migration.Checksum != "" && record.Checksum != "" && migration.Checksum != record.Checksum {
+			return &ChecksumFail{
+				Version: record.Version,
+				Name:    record.Name,
+				Applied: record.Checksum,
+				Current: migration.Checksum,
+			}
+		}
+	}
+	return nil
+}
+
+// pending returns the migrations not applied, up to the version.
+// Migrations older than the last applied one are not allowed.
+func (m *Migrator) pending(records []*Record, version int64) ([]*Migration, error) {
+	applied := map[int64]bool{}
+	var last int64
+	for _, record := range records {
+		applied[record.Version] = true
+		if record.Version > last {
+			last = record.Version
+		}
+	}
+
+	pending := []*Migration{}
+	for _, migration := range m.Migrations() {
+		if applied[migration.Version] || (version > 0 && migration.Version > version) {
+			continue
+		}
+		if migration.Version < last {
+			return nil, faults.Errorf("migration %d %s is older than the last applied migration %d", migration.Version, migration.Name, last)
+		}
+		pending = append(pending, migration)
+	}
+	return pending, nil
+}
+
+func (m *Migrator) apply(migration *Migration) error {
+	logger.Infof("Applying migration %d %s", migration.Version, migration.Name)
+	err := m.tm.Transaction(func(store db.IDb) error {
+		if err := migration.Up(store); err != nil {
+			return err
+		}
+		return m.history.add(store, migration)
+	})
+	if err != nil {
+		return faults.Errorf("applying migration %d %s: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+func (m *Migrator) revert(record *Record) error {
+	migration := m.migrations[record.Version]
+	if migration.Down == nil {
+		return faults.Errorf("migration %d %s cannot be undone: it has no Down", migration.Version, migration.Name)
+	}
+
+	logger.Infof("Undoing migration %d %s", migration.Version, migration.Name)
+	err := m.tm.Transaction(func(store db.IDb) error {
+		if err := migration.Down(store); err != nil {
+			return err
+		}
+		return m.history.remove(store, migration.Version)
+	})
+	if err != nil {
+		return faults.Errorf("undoing migration %d %s: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+// ensureTables creates the history and the lock tables, if they do not exist.
+// The DDL is executed outside of a transaction, since some databases do not allow to use a table created in the same transaction.
+func (m *Migrator) ensureTables() error {
+	store := m.tm.Store()
+	for _, table := range []*db.Table{m.history.table, m.lock.table} {
+		if exists(store, table) {
+			continue
+		}
+		if err := db.CreateTables(store, table); err != nil {
+			return faults.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// exists checks if the table exists by querying it
+func exists(store db.IDb, table *db.Table) bool {
+	var count int64
+	_, err := store.Query(table).CountAll().SelectInto(&count)
+	return err == nil
+}
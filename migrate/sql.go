@@ -0,0 +1,157 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/quintans/faults"
+	"github.com/quintans/goSQL/db"
+)
+
+// sqlFile matches the names of the SQL migration files, like 0001_create_book.up.sql
+var sqlFile = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// AddSQL registers the migrations of the SQL files in the directory of the file system, usually an embed.FS.
+//
+// The files are named <version>_<name>.up.sql and, optionally, <version>_<name>.down.sql.
+// Statements are separated by semicolons. Statements with semicolons inside, like PL/SQL blocks, must be written in Go.
+func (m *Migrator) AddSQL(fsys fs.FS, dir string) *Migrator {
+	if m.err != nil {
+		return m
+	}
+
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		m.err = faults.Wrap(err)
+		return m
+	}
+
+	ups := map[int64]*Migration{}
+	downs := map[int64]string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := sqlFile.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			m.err = faults.Errorf("invalid version in file %s: %w", entry.Name(), err)
+			return m
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			m.err = faults.Wrap(err)
+			return m
+		}
+		script := strings.ReplaceAll(string(data), "\r\n", "\n")
+
+		if match[3] == "down" {
+			downs[version] = script
+			continue
+		}
+		if other, ok := ups[version]; ok {
+			m.err = faults.Errorf("migrations %s and %s have the same version %d", other.Name, match[2], version)
+			return m
+		}
+		ups[version] = &Migration{
+			Version:  version,
+			Name:     match[2],
+			Up:       execScript(script),
+			Checksum: checksum(script),
+		}
+	}
+
+	for version := range downs {
+		if ups[version] == nil {
+			m.err = faults.Errorf("down migration %d without the up migration in %s", version, dir)
+			return m
+		}
+	}
+	for version, migration := range ups {
+		if script, ok := downs[version]; ok {
+			migration.Down = execScript(script)
+		}
+		m.AddMigration(migration)
+	}
+	return m
+}
+
+func checksum(script string) string {
+	sum := sha256.Sum256([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+func execScript(script string) func(store db.IDb) error {
+	return func(store db.IDb) error {
+		for _, stmt := range SplitStatements(script) {
+			logger.Debugf("SQL: %s", stmt)
+			if _, err := store.GetConnection().ExecContext(store.GetContext(), stmt); err != nil {
+				return faults.Errorf("executing statement\nSQL: %s: %w", stmt, err)
+			}
+		}
+		return nil
+	}
+}
+
+// SplitStatements splits the script in statements separated by semicolons.
+// Semicolons inside quotes or comments do not separate statements, and comments are removed.
+func SplitStatements(script string) []string {
+	stmts := []string{}
+	sb := strings.Builder{}
+	add := func() {
+		if stmt := strings.TrimSpace(sb.String()); stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+		sb.Reset()
+	}
+
+	runes := []rune(script)
+	for k := 0; k < len(runes); k++ {
+		c := runes[k]
+		switch {
+		case c == '\'' || c == '"':
+			// quoted text, where doubled quotes are escaped quotes
+			end := k + 1
+			for ; end < len(runes); end++ {
+				if runes[end] == c {
+					if end+1 < len(runes) && runes[end+1] == c {
+						end++
+						continue
+					}
+					break
+				}
+			}
+			if end >= len(runes) {
+				end = len(runes) - 1
+			}
+			sb.WriteString(string(runes[k : end+1]))
+			k = end
+		case c == '-' && k+1 < len(runes) && runes[k+1] == '-':
+			for k < len(runes) && runes[k] != '\n' {
+				k++
+			}
+			sb.WriteRune('\n')
+		case c == '/' && k+1 < len(runes) && runes[k+1] == '*':
+			k += 2
+			for k < len(runes) && !(runes[k] == '*' && k+1 < len(runes) && runes[k+1] == '/') {
+				k++
+			}
+			k++
+			sb.WriteRune(' ')
+		case c == ';':
+			add()
+		default:
+			sb.WriteRune(c)
+		}
+	}
+	add()
+	return stmts
+}
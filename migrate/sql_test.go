@@ -0,0 +1,138 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{name: "empty", script: "", want: []string{}},
+		{name: "only separators", script: " ;\n; ", want: []string{}},
+		{name: "single", script: "SELECT 1", want: []string{"SELECT 1"}},
+		{name: "separated", script: "SELECT 1;\nSELECT 2;\n", want: []string{"SELECT 1", "SELECT 2"}},
+		{name: "single quotes", script: "SELECT 'a;b'; SELECT 2", want: []string{"SELECT 'a;b'", "SELECT 2"}},
+		{name: "double quotes", script: `SELECT "a;b" FROM T; SELECT 2`, want: []string{`SELECT "a;b" FROM T`, "SELECT 2"}},
+		{name: "doubled quotes", script: "SELECT 'it''s; ok'; SELECT 2", want: []string{"SELECT 'it''s; ok'", "SELECT 2"}},
+		{name: "comment in quotes", script: "SELECT '-- a; /* b */'", want: []string{"SELECT '-- a; /* b */'"}},
+		{name: "line comment", script: "SELECT 1; -- a; b\nSELECT 2", want: []string{"SELECT 1", "SELECT 2"}},
+		{name: "line comment at end", script: "SELECT 1 -- a; b", want: []string{"SELECT 1"}},
+		{name: "block comment", script: "SELECT /* a; b */ 1; SELECT 2", want: []string{"SELECT   1", "SELECT 2"}},
+		{name: "unterminated quote", script: "SELECT 1; SELECT 'a;b", want: []string{"SELECT 1", "SELECT 'a;b"}},
+		{name: "unterminated block comment", script: "SELECT 1; /* a; b", want: []string{"SELECT 1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, SplitStatements(tt.script))
+		})
+	}
+}
+
+func TestAddSQL(t *testing.T) {
+	create := "CREATE TABLE BOOK (ID INTEGER);\n"
+	file := func(content string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(content)}
+	}
+
+	type migration struct {
+		version  int64
+		name     string
+		down     bool
+		checksum string
+	}
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		want    []migration
+		wantErr bool
+	}{
+		{
+			name: "paired",
+			files: fstest.MapFS{
+				"sql/0001_create_book.up.sql":   file(create),
+				"sql/0001_create_book.down.sql": file("DROP TABLE BOOK;"),
+				"sql/0002_fill_book.up.sql":     file("INSERT INTO BOOK VALUES (1);"),
+				"sql/README.md":                 file("not a migration"),
+				"sql/0003_other/0003_x.up.sql":  file("SELECT 1;"),
+			},
+			want: []migration{
+				{version: 1, name: "create_book", down: true, checksum: checksum(create)},
+				{version: 2, name: "fill_book", checksum: checksum("INSERT INTO BOOK VALUES (1);")},
+			},
+		},
+		{
+			name: "windows line endings",
+			files: fstest.MapFS{
+				"sql/0001_create_book.up.sql": file("CREATE TABLE BOOK (ID INTEGER);\r\n"),
+			},
+			want: []migration{
+				{version: 1, name: "create_book", checksum: checksum(create)},
+			},
+		},
+		{
+			name: "down without up",
+			files: fstest.MapFS{
+				"sql/0001_create_book.up.sql": file(create),
+				"sql/0002_fill_book.down.sql": file("DELETE FROM BOOK;"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "same version",
+			files: fstest.MapFS{
+				"sql/0001_create_book.up.sql": file(create),
+				"sql/1_create_other.up.sql":   file("CREATE TABLE OTHER (ID INTEGER);"),
+			},
+			wantErr: true,
+		},
+		{
+			name:    "missing directory",
+			files:   fstest.MapFS{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMigrator(nil).AddSQL(tt.files, "sql")
+			if tt.wantErr {
+				require.Error(t, m.err)
+				return
+			}
+			require.NoError(t, m.err)
+
+			got := []migration{}
+			for _, mig := range m.Migrations() {
+				require.NotNil(t, mig.Up)
+				got = append(got, migration{
+					version:  mig.Version,
+					name:     mig.Name,
+					down:     mig.Down != nil,
+					checksum: mig.Checksum,
+				})
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{name: "empty", script: "", want: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{name: "script", script: "abc", want: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, checksum(tt.script))
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package migrate
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/quintans/faults"
+	"github.com/quintans/goSQL/db"
+)
+
+const lockId = 1
+
+type historyTable struct {
+	table     *db.Table
+	version   *db.Column
+	name      *db.Column
+	checksum  *db.Column
+	appliedAt *db.Column
+}
+
+// newHistoryTable declares the history table in a schema of its own,
+// so that it is not part of the tables of the application, like the ones of RegisteredTables
+func newHistoryTable(name string) *historyTable {
+	t := db.NewSchema().TABLE(name)
+	return &historyTable{
+		table:     t,
+		version:   t.KEY("VERSION").Type(db.Bigint()),
+		name:      t.COLUMN("NAME").Type(db.Varchar(255)),
+		checksum:  t.COLUMN("CHECKSUM").Type(db.Varchar(64)).Nullable(),
+		appliedAt: t.COLUMN("APPLIED_AT").Type(db.Timestamp()),
+	}
+}
+
+// historyEntity maps a row of the history table. Oracle stores empty strings as NULL.
+type historyEntity struct {
+	Version   int64
+	Name      string
+	Checksum  *string
+	AppliedAt time.Time
+}
+
+func (h *historyTable) list(store db.IDb) ([]*Record, error) {
+	entities := []*historyEntity{}
+	err := store.Query(h.table).All().OrderBy(h.version).Asc().List(&entities)
+	if err != nil {
+		return nil, faults.Wrap(err)
+	}
+
+	records := make([]*Record, len(entities))
+	for k, e := range entities {
+		records[k] = &Record{
+			Version:   e.Version,
+			Name:      e.Name,
+			AppliedAt: e.AppliedAt,
+		}
+		if e.Checksum != nil {
+			records[k].Checksum = *e.Checksum
+		}
+	}
+	return records, nil
+}
+
+func (h *historyTable) add(store db.IDb, migration *Migration) error {
+	var checksum interface{}
+	if migration.Checksum != "" {
+		checksum = migration.Checksum
+	}
+	_, err := store.Insert(h.table).
+		Set(h.version, migration.Version).
+		Set(h.name, migration.Name).
+		Set(h.checksum, checksum).
+		Set(h.appliedAt, time.Now()).
+		ReturnId(false).
+		Execute()
+	return faults.Wrap(err)
+}
+
+func (h *historyTable) remove(store db.IDb, version int64) error {
+	_, err := store.Delete(h.table).Where(h.version.Matches(version)).Execute()
+	return faults.Wrap(err)
+}
+
+var _ error = &LockFail{}
+
+// LockFail is returned when the lock is held by another instance for longer than the lock timeout
+type LockFail struct {
+	LockedBy string
+	LockedAt time.Time
+}
+
+func (l *LockFail) Error() string {
+	return fmt.Sprintf("migrations are locked by %s since %s", l.LockedBy, l.LockedAt.Format(time.RFC3339))
+}
+
+// lockTable holds, at most, one row. The instance that inserts it holds the lock.
+type lockTable struct {
+	table    *db.Table
+	id       *db.Column
+	lockedBy *db.Column
+	lockedAt *db.Column
+}
+
+// newLockTable declares the lock table in a schema of its own, like the history table
+func newLockTable(name string) *lockTable {
+	t := db.NewSchema().TABLE(name)
+	return &lockTable{
+		table:    t,
+		id:       t.KEY("ID").Type(db.Integer()),
+		lockedBy: t.COLUMN("LOCKED_BY").Type(db.Varchar(255)),
+		lockedAt: t.COLUMN("LOCKED_AT").Type(db.Timestamp()),
+	}
+}
+
+// acquire inserts the lock row, retrying while it is held by another instance, until the timeout
+func (l *lockTable) acquire(store db.IDb, timeout time.Duration, interval time.Duration) error {
+	owner := lockOwner()
+	deadline := time.Now().Add(timeout)
+	for {
+		_, err := store.Insert(l.table).
+			Set(l.id, lockId).
+			Set(l.lockedBy, owner).
+			Set(l.lockedAt, time.Now()).
+			ReturnId(false).
+			Execute()
+		if err == nil {
+			return nil
+		}
+
+		// the insert fails if the lock row, of another instance, exists
+		var lockedBy string
+		var lockedAt time.Time
+		found, qerr := store.Query(l.table).
+			Column(l.lockedBy, l.lockedAt).
+			Where(l.id.Matches(lockId)).
+			SelectInto(&lockedBy, &lockedAt)
+		if qerr != nil || !found {
+			// it was not the lock row
+			return faults.Wrap(err)
+		}
+		if time.Now().After(deadline) {
+			return &LockFail{LockedBy: lockedBy, LockedAt: lockedAt}
+		}
+		logger.Infof("Waiting for the migration lock held by %s", lockedBy)
+		time.Sleep(interval)
+	}
+}
+
+func (l *lockTable) release(store db.IDb) error {
+	_, err := store.Delete(l.table).Where(l.id.Matches(lockId)).Execute()
+	return faults.Wrap(err)
+}
+
+func lockOwner() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}
@@ -6,8 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"regexp"
 	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"github.com/jinzhu/gorm"
@@ -15,6 +17,7 @@ import (
 	"github.com/quintans/faults"
 	"github.com/quintans/goSQL/db"
 	"github.com/quintans/goSQL/dbx"
+	"github.com/quintans/goSQL/migrate"
 	"github.com/quintans/toolkit/ext"
 	"github.com/quintans/toolkit/log"
 	"github.com/stretchr/testify/require"
@@ -113,6 +116,120 @@ func CreateTables(db *sql.DB, initSqlFile string) error {
 	return nil
 }
 
+// RequireTablesFixture asserts that the tables created by the fixture file have the same columns,
+// with the same types and nullability, and the same keys, as the DDL generated from the mapping,
+// so that the mapping and the fixture do not drift apart.
+func RequireTablesFixture(t *testing.T, translator db.Translator, fixtureFile string) {
+	content, err := ioutil.ReadFile(fixtureFile)
+	require.NoError(t, err)
+	fixture := ddlTables(strings.Split(string(content), ";\n"))
+	require.NotEmpty(t, fixture)
+
+	tables := []*db.Table{}
+	for _, table := range db.RegisteredTables() {
+		if _, ok := fixture[strings.ToUpper(table.GetName())]; ok {
+			tables = append(tables, table)
+		}
+	}
+	generated := ddlTables(translator.GetSqlForCreate(tables))
+	for name, columns := range fixture {
+		require.Contains(t, generated, name, "table %s is not mapped", name)
+		require.Equal(t, columns, generated[name], "table %s", name)
+	}
+}
+
+var (
+	createTableRe = regexp.MustCompile("(?is)^\\s*CREATE\\s+TABLE\\s+[\"`]?(\\w+)[\"`]?\\s*\\((.*)\\)")
+	primaryKeyRe  = regexp.MustCompile(`(?is)PRIMARY\s+KEY\s*\((.*)\)`)
+	typeRe        = regexp.MustCompile(`^(\w+)\s*(\([\d\s,]*\))?`)
+)
+
+// ddlTables returns the column definitions, by column name, of the tables created by the statements, by table name.
+// The types are normalized, so that the ones of different dialects can be compared.
+func ddlTables(stmts []string) map[string]map[string]string {
+	tables := map[string]map[string]string{}
+	for _, stmt := range stmts {
+		m := createTableRe.FindStringSubmatch(stmt)
+		if m == nil {
+			continue
+		}
+		columns := map[string]string{}
+		keys := []string{}
+		for _, def := range splitDefinitions(m[2]) {
+			upper := strings.ToUpper(def)
+			if strings.HasPrefix(upper, "PRIMARY") || strings.HasPrefix(upper, "CONSTRAINT") {
+				if k := primaryKeyRe.FindStringSubmatch(def); k != nil {
+					for _, key := range strings.Split(k[1], ",") {
+						keys = append(keys, ddlName(key))
+					}
+				}
+				continue
+			}
+			fields := strings.Fields(def)
+			if len(fields) < 2 {
+				continue
+			}
+			notNull := strings.Contains(upper, "NOT NULL")
+			columns[ddlName(fields[0])] = fmt.Sprintf("%s notNull=%t", ddlType(strings.Join(fields[1:], " ")), notNull)
+		}
+		for _, key := range keys {
+			columns[key] = strings.Replace(columns[key], "notNull=false", "notNull=true", 1) + " key"
+		}
+		tables[ddlName(m[1])] = columns
+	}
+	return tables
+}
+
+// splitDefinitions splits the body of a CREATE TABLE by the commas outside parenthesis
+func splitDefinitions(body string) []string {
+	defs := []string{}
+	depth, start := 0, 0
+	for i, c := range body {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				defs = append(defs, strings.TrimSpace(body[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(defs, strings.TrimSpace(body[start:]))
+}
+
+func ddlName(name string) string {
+	return strings.ToUpper(strings.Trim(strings.TrimSpace(name), "\"`"))
+}
+
+// ddlType returns the type family of a column definition, with its size
+func ddlType(def string) string {
+	m := typeRe.FindStringSubmatch(strings.ToUpper(def))
+	if m == nil {
+		return def
+	}
+	typ, size := m[1], strings.ReplaceAll(m[2], " ", "")
+	switch typ {
+	case "SMALLINT", "INTEGER", "INT", "BIGINT", "SERIAL", "BIGSERIAL":
+		return "INTEGER"
+	case "NUMBER", "NUMERIC", "DECIMAL":
+		if !strings.Contains(size, ",") {
+			return "INTEGER"
+		}
+		return "DECIMAL" + size
+	case "VARCHAR2":
+		return "VARCHAR" + size
+	case "DATETIME":
+		return "TIMESTAMP"
+	case "BYTEA", "BLOB", "LONGBLOB":
+		return "BINARY"
+	default:
+		return typ + size
+	}
+}
+
 const (
 	PUBLISHER_UTF8_NAME = "Edições Lusas"
 	AUTHOR_UTF8_NAME    = "Graça Tostão"
@@ -151,6 +268,19 @@ func (tt Tester) RunAll(t *testing.T) {
 	t.Run("RunUpdateSubquery", tt.RunUpdateSubquery)
 	t.Run("RunSimpleDelete", tt.RunSimpleDelete)
 	t.Run("RunStructDelete", tt.RunStructDelete)
+	t.Run("RunSubmitAll", tt.RunSubmitAll)
+	t.Run("RunBulkLoad", tt.RunBulkLoad)
+	t.Run("RunSavepoint", tt.RunSavepoint)
+	t.Run("RunTransactionWith", tt.RunTransactionWith)
+	t.Run("RunTransactionRetry", tt.RunTransactionRetry)
+	t.Run("RunTransactionHooks", tt.RunTransactionHooks)
+	t.Run("RunTransactionCtx", tt.RunTransactionCtx)
+	t.Run("RunOnReplica", tt.RunOnReplica)
+	t.Run("RunMandatory", tt.RunMandatory)
+	t.Run("RunColumnType", tt.RunColumnType)
+	t.Run("RunDDL", tt.RunDDL)
+	t.Run("RunMigration", tt.RunMigration)
+	t.Run("RunVerifySchema", tt.RunVerifySchema)
 	t.Run("RunSelectInto", tt.RunSelectInto)
 	t.Run("RunSelectTree", tt.RunSelectTree)
 	t.Run("RunSelectTreeTwoBranches", tt.RunSelectTreeTwoBranches)
@@ -171,8 +301,13 @@ func (tt Tester) RunAll(t *testing.T) {
 	t.Run("RunOuterFetchOrder", tt.RunOuterFetchOrder)
 	t.Run("RunOuterFetchOrderAs", tt.RunOuterFetchOrderAs)
 	t.Run("RunGroupBy", tt.RunGroupBy)
+	t.Run("RunGroupByRollup", tt.RunGroupByRollup)
+	t.Run("RunGroupingSets", tt.RunGroupingSets)
+	t.Run("RunDistinctOn", tt.RunDistinctOn)
 	t.Run("RunOrderBy", tt.RunOrderBy)
 	t.Run("RunPagination", tt.RunPagination)
+	t.Run("RunKeysetPagination", tt.RunKeysetPagination)
+	t.Run("RunListPage", tt.RunListPage)
 	t.Run("RunAssociationDiscriminator", tt.RunAssociationDiscriminator)
 	t.Run("RunAssociationDiscriminatorReverse", tt.RunAssociationDiscriminatorReverse)
 	t.Run("RunTableDiscriminator", tt.RunTableDiscriminator)
@@ -182,6 +317,14 @@ func (tt Tester) RunAll(t *testing.T) {
 	t.Run("RunRawSQL2", tt.RunRawSQL2)
 	t.Run("RunHaving", tt.RunHaving)
 	t.Run("RunUnion", tt.RunUnion)
+	t.Run("RunToSQL", tt.RunToSQL)
+	t.Run("RunGenerics", tt.RunGenerics)
+	t.Run("RunIterate", tt.RunIterate)
+	t.Run("RunNamingStrategy", tt.RunNamingStrategy)
+	t.Run("RunSchema", tt.RunSchema)
+	t.Run("RunIsNull", tt.RunIsNull)
+	t.Run("RunSoftDelete", tt.RunSoftDelete)
+	t.Run("RunIdentityMap", tt.RunIdentityMap)
 }
 
 func ResetDB(TM db.ITransactionManager) {
@@ -1097,6 +1240,649 @@ func (tt Tester) RunStructDelete(t *testing.T) {
 	}
 }
 
+func (tt Tester) RunSubmitAll(t *testing.T) {
+	ResetDB(tt.Tm)
+
+	err := tt.Tm.Transaction(func(store db.IDb) error {
+		var publishers []*Publisher
+		err := store.Query(PUBLISHER).All().Order(PUBLISHER_C_ID).List(&publishers)
+		require.NoError(t, err)
+		require.Len(t, publishers, 2)
+
+		publishers[0].Name = ext.String("Geek Books")
+		publishers[1].Name = ext.String("Lusas")
+		publishers[1].Version = 99 // invalid version
+		affected, err := store.Update(PUBLISHER).SubmitAll(publishers)
+		require.Equal(t, int64(1), affected)
+		var fail *dbx.BatchFail
+		require.True(t, errors.As(err, &fail))
+		require.Len(t, fail.Fails, 1)
+		var lockFail *dbx.OptimisticLockFail
+		require.True(t, errors.As(fail.Fails[1], &lockFail))
+		// the failures are also matched through the batch failure
+		require.ErrorAs(t, err, &lockFail)
+		require.Equal(t, int64(2), publishers[0].Version)
+
+		var name string
+		_, err = store.Query(PUBLISHER).Column(PUBLISHER_C_NAME).Where(PUBLISHER_C_ID.Matches(1)).SelectInto(&name)
+		require.NoError(t, err)
+		require.Equal(t, "Geek Books", name)
+
+		// the version is only checked for the elements with a version
+		publishers[1].Name = ext.String("Mixed Lusas")
+		publishers[1].Version = 0
+		affected, err = store.Update(PUBLISHER).SubmitAll(publishers)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), affected)
+		require.Equal(t, int64(3), publishers[0].Version)
+
+		_, err = store.Query(PUBLISHER).Column(PUBLISHER_C_NAME).Where(PUBLISHER_C_ID.Matches(2)).SelectInto(&name)
+		require.NoError(t, err)
+		require.Equal(t, "Mixed Lusas", name)
+
+		publishers[0].Version = 0
+		publishers[1].Version = 99 // invalid version
+		affected, err = store.Update(PUBLISHER).SubmitAll(publishers)
+		require.Equal(t, int64(1), affected)
+		require.True(t, errors.As(err, &fail))
+		require.Len(t, fail.Fails, 1)
+		require.ErrorAs(t, fail.Fails[1], &lockFail)
+
+		// the values set before the batch are used for every element
+		type publisherKey struct {
+			Id *int64
+		}
+		keys := []*publisherKey{{Id: publishers[0].Id}, {Id: publishers[1].Id}}
+		affected, err = store.Update(PUBLISHER).Set(PUBLISHER_C_NAME, "Batched").SubmitAll(keys)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), affected)
+
+		var count int64
+		_, err = store.Query(PUBLISHER).CountAll().Where(PUBLISHER_C_NAME.Matches("Batched")).SelectInto(&count)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), count)
+
+		// delete
+		created := []*Publisher{
+			{Name: ext.String("Batch 1")},
+			{Name: ext.String("Batch 2")},
+		}
+		for _, p := range created {
+			require.NoError(t, store.Create(p))
+		}
+		affected, err = store.Delete(PUBLISHER).SubmitAll(created)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), affected)
+
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func (tt Tester) RunBulkLoad(t *testing.T) {
+	ResetDB(tt.Tm)
+
+	publishers := []*Publisher{}
+	for i := int64(10); i < 13; i++ {
+		p := &Publisher{Name: ext.String(fmt.Sprintf("Bulk\tçã %d", i))}
+		p.Id = ext.Int64(i)
+		publishers = append(publishers, p)
+	}
+	publishers[2].Name = nil
+
+	// COPY FROM STDIN must run inside a transaction
+	if tt.DbName == Postgres {
+		_, err := tt.Tm.Store().BulkLoad(PUBLISHER, db.SliceRows(publishers))
+		require.Error(t, err)
+	}
+
+	err := tt.Tm.Transaction(func(store db.IDb) error {
+		affected, err := store.BulkLoad(PUBLISHER, db.SliceRows(publishers))
+		require.NoError(t, err)
+		require.Equal(t, int64(3), affected)
+		return nil
+	})
+	require.NoError(t, err)
+
+	var loaded []*Publisher
+	err = tt.Tm.Store().Query(PUBLISHER).
+		All().
+		Where(PUBLISHER_C_ID.GreaterOrMatch(10)).
+		Order(PUBLISHER_C_ID).
+		List(&loaded)
+	require.NoError(t, err)
+	require.Len(t, loaded, 3)
+	require.Equal(t, "Bulk\tçã 10", *loaded[0].Name)
+	require.Equal(t, int64(1), loaded[1].Version)
+	require.Nil(t, loaded[2].Name)
+}
+
+func (tt Tester) RunSavepoint(t *testing.T) {
+	ResetDB(tt.Tm)
+
+	publisherName := func(store db.IDb) string {
+		var name string
+		_, err := store.Query(PUBLISHER).Column(PUBLISHER_C_NAME).Where(PUBLISHER_C_ID.Matches(1)).SelectInto(&name)
+		require.NoError(t, err)
+		return name
+	}
+	rename := func(store db.IDb, name string) {
+		_, err := store.Update(PUBLISHER).Set(PUBLISHER_C_NAME, name).Where(PUBLISHER_C_ID.Matches(1)).Execute()
+		require.NoError(t, err)
+	}
+
+	err := tt.Tm.Transaction(func(store db.IDb) error {
+		rename(store, "First")
+		require.NoError(t, store.Savepoint("before_second"))
+		rename(store, "Second")
+		require.NoError(t, store.RollbackTo("before_second"))
+		require.Equal(t, "First", publisherName(store))
+		require.NoError(t, store.Release("before_second"))
+
+		// a failed nested transaction only undoes its own work
+		err := tt.Tm.With(store).Transaction(func(store db.IDb) error {
+			rename(store, "Nested")
+			return errors.New("nested failure")
+		})
+		require.Error(t, err)
+		require.Equal(t, "First", publisherName(store))
+
+		// a successful nested transaction keeps its work
+		err = tt.Tm.With(store).Transaction(func(store db.IDb) error {
+			rename(store, "Nested")
+			return nil
+		})
+		require.NoError(t, err)
+
+		// the enclosing transaction is also found through the context of its store
+		err = tt.Tm.TransactionWith(store.GetContext(), nil, func(nested db.IDb) error {
+			require.Same(t, store.GetConnection(), nested.GetConnection())
+			rename(nested, "Context")
+			return errors.New("nested failure")
+		})
+		require.Error(t, err)
+		require.Equal(t, "Nested", publisherName(store))
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Nested", publisherName(tt.Tm.Store()))
+
+	err = tt.Tm.Store().Savepoint("outside")
+	require.Error(t, err)
+}
+
+func (tt Tester) RunTransactionWith(t *testing.T) {
+	ResetDB(tt.Tm)
+
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "value")
+	err := tt.Tm.TransactionWith(ctx, nil, func(store db.IDb) error {
+		require.Equal(t, "value", store.GetContext().Value(key{}))
+		_, err := store.Update(PUBLISHER).Set(PUBLISHER_C_NAME, "Serial").Where(PUBLISHER_C_ID.Matches(1)).Execute()
+		if err != nil {
+			return err
+		}
+
+		// the context is bound to the store of the nested transaction, and restored after
+		nested := context.WithValue(ctx, key{}, "nested")
+		err = tt.Tm.With(store).TransactionWith(nested, nil, func(store db.IDb) error {
+			require.Equal(t, "nested", store.GetContext().Value(key{}))
+			return nil
+		})
+		require.Equal(t, "value", store.GetContext().Value(key{}))
+		return err
+	})
+	require.NoError(t, err)
+
+	if tt.DbName == Postgres || tt.DbName == MySQL {
+		opts := &sql.TxOptions{Isolation: sql.LevelSerializable}
+		err = tt.Tm.TransactionWith(context.Background(), opts, func(store db.IDb) error {
+			var name string
+			_, err := store.Query(PUBLISHER).Column(PUBLISHER_C_NAME).Where(PUBLISHER_C_ID.Matches(1)).SelectInto(&name)
+			require.Equal(t, "Serial", name)
+			return err
+		})
+		require.NoError(t, err)
+	}
+
+	// a cancelled context aborts the transaction
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	called := false
+	err = tt.Tm.TransactionWith(cancelled, nil, func(store db.IDb) error {
+		called = true
+		return nil
+	})
+	require.Error(t, err)
+	require.False(t, called)
+}
+
+func (tt Tester) RunTransactionRetry(t *testing.T) {
+	if tt.DbName != Postgres {
+		return
+	}
+	ResetDB(tt.Tm)
+
+	store := tt.Tm.Store()
+	retrying := db.NewTransactionManager(store.GetConnection().(*sql.DB), store.GetTranslator(), db.TmWithRetry(3, nil))
+
+	attempts := 0
+	opts := &sql.TxOptions{Isolation: sql.LevelSerializable}
+	err := retrying.TransactionWith(context.Background(), opts, func(store db.IDb) error {
+		attempts++
+		var name string
+		_, err := store.Query(PUBLISHER).Column(PUBLISHER_C_NAME).Where(PUBLISHER_C_ID.Matches(1)).SelectInto(&name)
+		require.NoError(t, err)
+
+		if attempts == 1 {
+			// concurrent update, in another transaction, of the row that was read
+			err := tt.Tm.Transaction(func(other db.IDb) error {
+				_, err := other.Update(PUBLISHER).Set(PUBLISHER_C_NAME, "Concurrent").Where(PUBLISHER_C_ID.Matches(1)).Execute()
+				return err
+			})
+			require.NoError(t, err)
+		}
+
+		_, err = store.Update(PUBLISHER).Set(PUBLISHER_C_NAME, "Retried").Where(PUBLISHER_C_ID.Matches(1)).Execute()
+		return err
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, attempts)
+
+	var name string
+	_, err = tt.Tm.Store().Query(PUBLISHER).Column(PUBLISHER_C_NAME).Where(PUBLISHER_C_ID.Matches(1)).SelectInto(&name)
+	require.NoError(t, err)
+	require.Equal(t, "Retried", name)
+}
+
+func (tt Tester) RunTransactionHooks(t *testing.T) {
+	ResetDB(tt.Tm)
+
+	var events []string
+	hook := func(event string) func() {
+		return func() {
+			events = append(events, event)
+		}
+	}
+
+	err := tt.Tm.Transaction(func(store db.IDb) error {
+		store.OnCommit(hook("commit"))
+		store.OnRollback(hook("rollback"))
+
+		// the hooks of a failed nested transaction are discarded
+		err := tt.Tm.With(store).Transaction(func(store db.IDb) error {
+			store.OnCommit(hook("nested commit"))
+			store.OnRollback(hook("nested rollback"))
+			return errors.New("nested failure")
+		})
+		require.Error(t, err)
+		require.Equal(t, []string{"nested rollback"}, events)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"nested rollback", "commit"}, events)
+
+	events = nil
+	failure := errors.New("failure")
+	err = tt.Tm.Transaction(func(store db.IDb) error {
+		store.OnCommit(hook("commit"))
+		store.OnRollback(hook("rollback"))
+		return failure
+	})
+	require.ErrorIs(t, err, failure)
+	require.Equal(t, []string{"rollback"}, events)
+
+	// outside a transaction, commit hooks run immediately
+	events = nil
+	tt.Tm.Store().OnCommit(hook("commit"))
+	require.Equal(t, []string{"commit"}, events)
+}
+
+func (tt Tester) RunTransactionCtx(t *testing.T) {
+	ResetDB(tt.Tm)
+
+	publisherName := func(store db.IDb) string {
+		var name string
+		_, err := store.Query(PUBLISHER).Column(PUBLISHER_C_NAME).Where(PUBLISHER_C_ID.Matches(1)).SelectInto(&name)
+		require.NoError(t, err)
+		return name
+	}
+	rename := func(store db.IDb, name string) error {
+		_, err := store.Update(PUBLISHER).Set(PUBLISHER_C_NAME, name).Where(PUBLISHER_C_ID.Matches(1)).Execute()
+		return err
+	}
+
+	err := tt.Tm.TransactionCtx(context.Background(), db.PROPAGATION_REQUIRED, func(ctx context.Context, outer db.IDb) error {
+		require.NoError(t, rename(outer, "Outer"))
+
+		// joins the transaction in the context
+		err := tt.Tm.TransactionCtx(ctx, db.PROPAGATION_REQUIRED, func(ctx context.Context, store db.IDb) error {
+			require.True(t, store == outer)
+			require.Equal(t, "Outer", publisherName(store))
+			return nil
+		})
+		require.NoError(t, err)
+
+		// a failed nested transaction only undoes its own work
+		err = tt.Tm.TransactionCtx(ctx, db.PROPAGATION_NESTED, func(ctx context.Context, store db.IDb) error {
+			require.NoError(t, rename(store, "Nested"))
+			return errors.New("nested failure")
+		})
+		require.Error(t, err)
+		require.Equal(t, "Outer", publisherName(outer))
+
+		// a new transaction does not join the transaction in the context
+		err = tt.Tm.TransactionCtx(ctx, db.PROPAGATION_REQUIRES_NEW, func(ctx context.Context, store db.IDb) error {
+			require.False(t, store == outer)
+			return nil
+		})
+		require.NoError(t, err)
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Outer", publisherName(tt.Tm.Store()))
+
+	// without a transaction in the context
+	err = tt.Tm.TransactionCtx(context.Background(), db.PROPAGATION_SUPPORTS, func(ctx context.Context, store db.IDb) error {
+		require.Error(t, store.Savepoint("none"))
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func (tt Tester) RunOnReplica(t *testing.T) {
+	ResetDB(tt.Tm)
+
+	// without replicas, the queries go to the primary
+	var publishers []*Publisher
+	err := tt.Tm.Store().Query(PUBLISHER).All().OnReplica().List(&publishers)
+	require.NoError(t, err)
+	require.Len(t, publishers, 2)
+
+	// the policy is only asked for a replica when the query goes to the replicas
+	var onReplica int
+	counting := func(size int) int {
+		onReplica++
+		return 0
+	}
+	store := tt.Tm.Store()
+	primary := store.GetConnection().(*sql.DB)
+	window := 200 * time.Millisecond
+	replicated := db.NewTransactionManager(primary, store.GetTranslator(),
+		db.TmWithReplicas(counting, primary), db.TmWithStickyPrimary(window))
+	list := func(query *db.Query) {
+		var publishers []*Publisher
+		require.NoError(t, query.List(&publishers))
+		require.Len(t, publishers, 2)
+	}
+
+	list(replicated.Store().Query(PUBLISHER).All())
+	require.Equal(t, 0, onReplica)
+	list(replicated.Store().Query(PUBLISHER).All().OnReplica())
+	require.Equal(t, 1, onReplica)
+
+	// all the queries without a transaction go to the replicas
+	err = replicated.NoTransaction(func(store db.IDb) error {
+		list(store.Query(PUBLISHER).All())
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, onReplica)
+
+	// inside a transaction the queries go to the primary
+	err = replicated.Transaction(func(store db.IDb) error {
+		list(store.Query(PUBLISHER).All().OnReplica())
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, onReplica)
+
+	// after a write, the queries go to the primary during the sticky window
+	ctx := db.StickyPrimaryContext(context.Background())
+	err = replicated.NoTransaction(func(store db.IDb) error {
+		store.WithContext(ctx)
+		list(store.Query(PUBLISHER).All())
+		require.Equal(t, 3, onReplica)
+
+		_, err := store.Update(PUBLISHER).Set(PUBLISHER_C_NAME, "Sticky").Where(PUBLISHER_C_ID.Matches(1)).Execute()
+		require.NoError(t, err)
+
+		var name string
+		_, err = store.Query(PUBLISHER).Column(PUBLISHER_C_NAME).Where(PUBLISHER_C_ID.Matches(1)).SelectInto(&name)
+		require.NoError(t, err)
+		require.Equal(t, "Sticky", name)
+		require.Equal(t, 3, onReplica)
+
+		time.Sleep(window)
+		list(store.Query(PUBLISHER).All())
+		require.Equal(t, 4, onReplica)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// without a policy the replicas are used in turn
+	policy := db.RoundRobinPolicy()
+	require.Equal(t, []int{0, 1, 0}, []int{policy(2), policy(2), policy(2)})
+}
+
+func (tt Tester) RunMandatory(t *testing.T) {
+	ResetDB(tt.Tm)
+
+	store := tt.Tm.Store()
+	countBins := func() int64 {
+		var count int64
+		_, err := store.Query(BOOK_BIN).CountAll().SelectInto(&count)
+		require.NoError(t, err)
+		return count
+	}
+	before := countBins()
+
+	var fail *db.MandatoryFail
+	_, err := store.Insert(BOOK_BIN).Submit(&BookBin{})
+	require.ErrorAs(t, err, &fail)
+	require.Equal(t, []string{"HARDCOVER"}, fail.Columns)
+	require.Equal(t, before, countBins())
+
+	_, err = store.Update(BOOK_BIN).Set(BOOK_BIN_C_HARDCOVER, nil).Where(BOOK_BIN_C_ID.Matches(1)).Execute()
+	require.ErrorAs(t, err, &fail)
+
+	// columns that are not being updated are not verified
+	_, err = store.Update(BOOK_BIN).Set(BOOK_BIN_C_VERSION, 2).Where(BOOK_BIN_C_ID.Matches(1)).Execute()
+	require.NoError(t, err)
+}
+
+func (tt Tester) RunColumnType(t *testing.T) {
+	ResetDB(tt.Tm)
+
+	store := tt.Tm.Store()
+	require.Equal(t, db.Varchar(50), *PUBLISHER_C_NAME.GetType())
+	require.True(t, PUBLISHER_C_NAME.IsNullable())
+
+	var fail *db.TypeFail
+	_, err := store.Insert(PUBLISHER).Submit(&Publisher{Name: ext.String(strings.Repeat("a", 51))})
+	require.ErrorAs(t, err, &fail)
+	require.Len(t, fail.Violations, 1)
+	require.Equal(t, "NAME", fail.Violations[0].Column)
+
+	_, err = store.Update(PUBLISHER).Set(PUBLISHER_C_NAME, strings.Repeat("b", 51)).Where(PUBLISHER_C_ID.Matches(1)).Execute()
+	require.ErrorAs(t, err, &fail)
+
+	// the statement is validated as the execution
+	_, err = store.Update(PUBLISHER).Set(PUBLISHER_C_NAME, strings.Repeat("b", 51)).Where(PUBLISHER_C_ID.Matches(1)).ToSQL()
+	require.ErrorAs(t, err, &fail)
+	update := store.Update(PUBLISHER).Set(PUBLISHER_C_NAME, "Valid").Where(PUBLISHER_C_ID.Matches(1))
+	_, err = update.ToSQL()
+	require.NoError(t, err)
+	affected, err := update.Execute()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), affected)
+
+	// nullable
+	_, err = store.Update(PUBLISHER).Set(PUBLISHER_C_NAME, nil).Where(PUBLISHER_C_ID.Matches(1)).Execute()
+	require.NoError(t, err)
+}
+
+func (tt Tester) RunDDL(t *testing.T) {
+	store := tt.Tm.Store()
+	// leftovers of a previous failed run
+	_ = db.DropTables(store, GADGET_PART, GADGET)
+
+	err := db.CreateTables(store, GADGET, GADGET_PART)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.DropTables(store, GADGET_PART, GADGET))
+	}()
+
+	gadget := &Gadget{Name: ext.String("Widget")}
+	_, err = store.Insert(GADGET).Submit(gadget)
+	require.NoError(t, err)
+	require.NotNil(t, gadget.Id)
+
+	part := &GadgetPart{GadgetId: gadget.Id, Name: ext.String("Gear")}
+	_, err = store.Insert(GADGET_PART).Submit(part)
+	require.NoError(t, err)
+
+	var count int64
+	_, err = store.Query(GADGET_PART).CountAll().Inner(GADGET_PART_A_GADGET).Join().SelectInto(&count)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+
+	// unique constraint
+	_, err = store.Insert(GADGET).Submit(&Gadget{Name: ext.String("Widget")})
+	require.Error(t, err)
+
+	// foreign key
+	_, err = store.Insert(GADGET_PART).Submit(&GadgetPart{GadgetId: ext.Int64(-1), Name: ext.String("Orphan")})
+	require.Error(t, err)
+}
+
+func (tt Tester) RunMigration(t *testing.T) {
+	sqlFiles := func(insert string) fstest.MapFS {
+		return fstest.MapFS{
+			"sql/0002_add_gadget.up.sql":   {Data: []byte("-- the first gadget\n" + insert)},
+			"sql/0002_add_gadget.down.sql": {Data: []byte("DELETE FROM GADGET WHERE ID = 1;")},
+		}
+	}
+	createGadget := func(store db.IDb) error {
+		return db.CreateTables(store, GADGET)
+	}
+	dropGadget := func(store db.IDb) error {
+		return db.DropTables(store, GADGET)
+	}
+	countGadgets := func() int64 {
+		var count int64
+		_, err := tt.Tm.Store().Query(GADGET).CountAll().SelectInto(&count)
+		require.NoError(t, err)
+		return count
+	}
+
+	m := migrate.NewMigrator(tt.Tm).
+		Add(1, "create gadget", createGadget, dropGadget).
+		AddSQL(sqlFiles("INSERT INTO GADGET (ID, VERSION, NAME) VALUES (1, 1, 'one');"), "sql").
+		Add(3, "locked", func(store db.IDb) error {
+			// another instance cannot migrate while this one is migrating
+			err := migrate.NewMigrator(tt.Tm, migrate.WithLockTimeout(0)).Up()
+			var fail *migrate.LockFail
+			require.True(t, errors.As(err, &fail), "expected LockFail, got %+v", err)
+			return nil
+		}, func(store db.IDb) error {
+			return nil
+		})
+	// leftovers of a previous failed run
+	_ = m.Unlock()
+
+	// the migration tables are not part of the application tables
+	for _, table := range db.RegisteredTables() {
+		require.NotEqual(t, migrate.HISTORY_TABLE, table.GetName())
+		require.NotEqual(t, migrate.LOCK_TABLE, table.GetName())
+	}
+
+	err := m.Up()
+	require.NoError(t, err)
+	records, err := m.History()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	require.Equal(t, int64(2), records[1].Version)
+	require.Equal(t, "add_gadget", records[1].Name)
+	require.NotEmpty(t, records[1].Checksum)
+	require.Empty(t, records[0].Checksum)
+	require.Equal(t, int64(1), countGadgets())
+
+	pending, err := m.Pending()
+	require.NoError(t, err)
+	require.Empty(t, pending)
+
+	err = m.DownTo(1)
+	require.NoError(t, err)
+	records, err = m.History()
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, int64(0), countGadgets())
+
+	err = m.UpTo(2)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), countGadgets())
+
+	// the applied SQL migration was edited
+	changed := migrate.NewMigrator(tt.Tm).
+		Add(1, "create gadget", createGadget, dropGadget).
+		AddSQL(sqlFiles("INSERT INTO GADGET (ID, VERSION, NAME) VALUES (1, 1, 'two');"), "sql")
+	err = changed.Up()
+	var fail *migrate.ChecksumFail
+	require.True(t, errors.As(err, &fail), "expected ChecksumFail, got %+v", err)
+	require.Equal(t, int64(2), fail.Version)
+
+	err = m.DownTo(0)
+	require.NoError(t, err)
+	records, err = m.History()
+	require.NoError(t, err)
+	require.Empty(t, records)
+}
+
+func (tt Tester) RunVerifySchema(t *testing.T) {
+	store := tt.Tm.Store()
+	// leftovers of a previous failed run
+	_ = db.DropTables(store, GADGET_PART)
+	_ = db.DropTables(store, GADGET)
+
+	err := db.VerifySchema(store, GADGET, GADGET_PART)
+	var fail *db.SchemaFail
+	require.True(t, errors.As(err, &fail), "expected SchemaFail, got %+v", err)
+	require.Len(t, fail.Drifts, 2)
+	require.Equal(t, db.DRIFT_MISSING_TABLE, fail.Drifts[0].Kind)
+	require.Equal(t, db.DRIFT_MISSING_TABLE, fail.Drifts[1].Kind)
+
+	err = db.CreateTables(store, GADGET, GADGET_PART)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.DropTables(store, GADGET))
+	}()
+	err = db.VerifySchema(store, GADGET, GADGET_PART)
+	require.NoError(t, err)
+
+	// GADGET_PART without NAME, primary key and foreign key
+	err = db.DropTables(store, GADGET_PART)
+	require.NoError(t, err)
+	_, err = store.GetConnection().ExecContext(store.GetContext(), "CREATE TABLE GADGET_PART (ID INTEGER NOT NULL, VERSION INTEGER, GADGET_ID INTEGER)")
+	require.NoError(t, err)
+	defer func() {
+		_, err := store.GetConnection().ExecContext(store.GetContext(), "DROP TABLE GADGET_PART")
+		require.NoError(t, err)
+	}()
+
+	err = db.VerifySchema(store, GADGET, GADGET_PART)
+	require.True(t, errors.As(err, &fail), "expected SchemaFail, got %+v", err)
+	kinds := map[db.DriftKind]db.Drift{}
+	for _, d := range fail.Drifts {
+		kinds[d.Kind] = d
+	}
+	require.Len(t, fail.Drifts, 3)
+	require.Equal(t, "NAME", kinds[db.DRIFT_MISSING_COLUMN].Column)
+	require.Contains(t, kinds, db.DRIFT_KEY_MISMATCH)
+	require.Contains(t, kinds, db.DRIFT_MISSING_FOREIGN_KEY)
+}
+
 func (tt Tester) RunSelectInto(t *testing.T) {
 	ResetDB(tt.Tm)
 
@@ -1735,6 +2521,126 @@ func (tt Tester) RunGroupBy(t *testing.T) {
 	}
 }
 
+func (tt Tester) RunGroupByRollup(t *testing.T) {
+	ResetDB(tt.Tm)
+
+	store := tt.Tm.Store()
+	totals := map[int64]float64{}
+	var publisher sql.NullInt64
+	var total float64
+	err := store.Query(BOOK).
+		Column(BOOK_C_PUBLISHER_ID).
+		Column(db.Sum(BOOK_C_PRICE)).
+		GroupByRollup(BOOK_C_PUBLISHER_ID).
+		ListSimple(func() {
+			// the grand total has a null publisher
+			totals[publisher.Int64] = total
+		}, &publisher, &total)
+	// Firebird does not support ROLLUP
+	if tt.DbName == Firebird {
+		requireNotSupported(t, err, "ROLLUP")
+		return
+	}
+	require.NoError(t, err)
+	require.Len(t, totals, 3)
+	require.Equal(t, 34.5, totals[1])
+	require.Equal(t, 19.0, totals[2])
+	require.Equal(t, 53.5, totals[0])
+}
+
+func (tt Tester) RunGroupingSets(t *testing.T) {
+	ResetDB(tt.Tm)
+
+	// only available in PostgreSQL and Oracle
+	unsupported := tt.DbName == Firebird || tt.DbName == MySQL
+
+	store := tt.Tm.Store()
+	var rows int
+	var grandTotals int
+	var publisher sql.NullInt64
+	var grouping int64
+	var total float64
+	err := store.Query(BOOK).
+		Column(BOOK_C_PUBLISHER_ID).
+		Column(db.Grouping(BOOK_C_PUBLISHER_ID)).
+		Column(db.Sum(BOOK_C_PRICE)).
+		GroupingSets([]*db.Column{BOOK_C_PUBLISHER_ID}, nil).
+		ListSimple(func() {
+			rows++
+			if grouping == 1 {
+				grandTotals++
+				require.Equal(t, 53.5, total)
+			}
+		}, &publisher, &grouping, &total)
+	if unsupported {
+		requireNotSupported(t, err, "GROUPING SETS")
+	} else {
+		require.NoError(t, err)
+		require.Equal(t, 3, rows)
+		require.Equal(t, 1, grandTotals)
+	}
+
+	rows = 0
+	err = store.Query(BOOK).
+		Column(BOOK_C_PUBLISHER_ID).
+		Column(db.Sum(BOOK_C_PRICE)).
+		GroupByCube(BOOK_C_PUBLISHER_ID).
+		ListSimple(func() {
+			rows++
+		}, &publisher, &total)
+	if unsupported {
+		requireNotSupported(t, err, "CUBE")
+	} else {
+		require.NoError(t, err)
+		require.Equal(t, 3, rows)
+	}
+
+	grouping = 0
+	err = store.Query(BOOK).
+		Column(BOOK_C_PUBLISHER_ID).
+		Column(db.Grouping(BOOK_C_PUBLISHER_ID)).
+		GroupBy(BOOK_C_PUBLISHER_ID).
+		ListSimple(func() {
+			require.Equal(t, int64(0), grouping)
+		}, &publisher, &grouping)
+	if unsupported {
+		requireNotSupported(t, err, "GROUPING")
+	} else {
+		require.NoError(t, err)
+	}
+}
+
+// requireNotSupported asserts that the query was rejected, before execution, for using the feature
+func requireNotSupported(t *testing.T, err error, feature string) {
+	t.Helper()
+	var fail *db.NotSupportedFail
+	require.ErrorAs(t, err, &fail)
+	require.Equal(t, feature, fail.Feature)
+}
+
+func (tt Tester) RunDistinctOn(t *testing.T) {
+	ResetDB(tt.Tm)
+
+	store := tt.Tm.Store()
+	// the most expensive book of each publisher
+	var books []*Book
+	err := store.Query(BOOK).
+		All().
+		DistinctOn(BOOK_C_PUBLISHER_ID).
+		Order(BOOK_C_PUBLISHER_ID).
+		Order(BOOK_C_PRICE).Desc().
+		List(&books)
+	// MySQL 5 does not support window functions
+	if tt.DbName == MySQL {
+		requireNotSupported(t, err, "DISTINCT ON")
+		return
+	}
+	require.NoError(t, err)
+	require.Len(t, books, 2)
+	require.Equal(t, int64(1), *books[0].Id)
+	require.Equal(t, int64(2), *books[1].Id)
+}
+
 func (tt Tester) RunOrderBy(t *testing.T) {
 	ResetDB(tt.Tm)
 
@@ -1795,6 +2701,138 @@ func (tt Tester) RunPagination(t *testing.T) {
 	}
 }
 
+func (tt Tester) RunKeysetPagination(t *testing.T) {
+	ResetDB(tt.Tm)
+
+	store := tt.Tm.Store()
+	page := func(cursor string, before bool) ([]*Book, db.Cursors) {
+		query := store.Query(BOOK).
+			All().
+			Order(BOOK_C_PRICE).Desc().
+			Order(BOOK_C_ID)
+		if before {
+			query = query.Before(cursor)
+		} else {
+			query = query.After(cursor)
+		}
+		var books []*Book
+		cursors, err := query.Limit(2).ListWithCursors(&books)
+		require.NoError(t, err)
+		return books, cursors
+	}
+
+	books, cursors := page("", false)
+	require.Len(t, books, 2)
+	require.Equal(t, int64(1), *books[0].Id)
+	require.Equal(t, int64(2), *books[1].Id)
+
+	books, cursors = page(cursors.Next, false)
+	require.Len(t, books, 1)
+	require.Equal(t, int64(3), *books[0].Id)
+
+	books, _ = page(cursors.Prev, true)
+	require.Len(t, books, 2)
+	require.Equal(t, int64(1), *books[0].Id)
+	require.Equal(t, int64(2), *books[1].Id)
+
+	// ordering by a column of a joined table
+	books = nil
+	cursors, err := store.Query(BOOK).
+		All().
+		Inner(BOOK_A_PUBLISHER).OrderBy(PUBLISHER_C_NAME).Join().
+		Order(BOOK_C_ID).
+		After("").
+		Limit(2).
+		ListWithCursors(&books)
+	require.NoError(t, err)
+	require.Len(t, books, 2)
+	require.Equal(t, int64(2), *books[0].Id)
+	require.Equal(t, int64(3), *books[1].Id)
+
+	books = nil
+	_, err = store.Query(BOOK).
+		All().
+		Inner(BOOK_A_PUBLISHER).OrderBy(PUBLISHER_C_NAME).Join().
+		Order(BOOK_C_ID).
+		After(cursors.Next).
+		Limit(2).
+		ListWithCursors(&books)
+	require.NoError(t, err)
+	require.Len(t, books, 1)
+	require.Equal(t, int64(1), *books[0].Id)
+}
+
+func (tt Tester) RunListPage(t *testing.T) {
+	ResetDB(tt.Tm)
+
+	store := tt.Tm.Store()
+	var books []*Book
+	page, err := store.Query(BOOK).
+		All().
+		Order(BOOK_C_ID).
+		Skip(1).
+		Limit(1).
+		ListPage(&books)
+	require.NoError(t, err)
+	require.Len(t, books, 1)
+	require.Equal(t, int64(2), *books[0].Id)
+	require.Equal(t, books, page.Items)
+	require.Equal(t, int64(3), page.Total)
+	require.Equal(t, int64(1), page.Skip)
+	require.Equal(t, int64(1), page.Limit)
+
+	// with joins
+	books = nil
+	page, err = store.Query(BOOK).
+		All().
+		Inner(BOOK_A_PUBLISHER).
+		On(PUBLISHER_C_NAME.Like("Edi%")).
+		Join().
+		Order(BOOK_C_ID).
+		Limit(1).
+		ListPage(&books)
+	require.NoError(t, err)
+	require.Len(t, books, 1)
+	require.Equal(t, int64(2), page.Total)
+
+	// with distinct
+	var ids []int64
+	page, err = store.Query(BOOK).
+		Distinct().
+		Column(BOOK_C_PUBLISHER_ID).
+		Order(BOOK_C_PUBLISHER_ID).
+		Limit(1).
+		ListPage(&ids)
+	require.NoError(t, err)
+	require.Equal(t, []int64{1}, ids)
+	require.Equal(t, int64(2), page.Total)
+
+	// with group by
+	var prices []float64
+	page, err = store.Query(BOOK).
+		Column(db.Sum(BOOK_C_PRICE)).
+		Column(BOOK_C_PUBLISHER_ID).
+		GroupByPos(2).
+		Order(BOOK_C_PUBLISHER_ID).
+		Limit(1).
+		ListPage(&prices)
+	require.NoError(t, err)
+	require.Len(t, prices, 1)
+	require.Equal(t, int64(2), page.Total)
+
+	// the pagination of fetched associations would be of the joined rows
+	var publishers []*Publisher
+	_, err = store.Query(PUBLISHER).
+		All().
+		Outer(PUBLISHER_A_BOOKS).
+		Fetch().
+		Order(PUBLISHER_C_ID).
+		Limit(2).
+		ListPage(&publishers)
+	require.Error(t, err)
+	require.Empty(t, publishers)
+}
+
 func (tt Tester) RunAssociationDiscriminator(t *testing.T) {
 	ResetDB2(tt.Tm)
 
@@ -2251,3 +3289,413 @@ func (tt Tester) RunEmbeddedPtr(t *testing.T) {
 		t.Fatalf("Expected %+v, got %+v", fn, p2.FullName)
 	}
 }
+
+func (tt Tester) RunToSQL(t *testing.T) {
+	ResetDB(tt.Tm)
+
+	store := tt.Tm.Store()
+	stmt, err := store.Query(BOOK).
+		Column(BOOK_C_ID).
+		Where(BOOK_C_NAME.Like("%a%")).
+		Order(BOOK_C_ID).
+		ToSQL()
+	require.NoError(t, err)
+	require.Contains(t, stmt.OriSql, ":")
+	require.Equal(t, []interface{}{"%a%"}, stmt.Args)
+
+	// the statement can be executed elsewhere
+	rows, err := store.GetConnection().QueryContext(context.Background(), stmt.Sql, stmt.Args...)
+	require.NoError(t, err)
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		require.NoError(t, rows.Scan(&id))
+		ids = append(ids, id)
+	}
+	require.NoError(t, rows.Close())
+	require.Equal(t, []int64{1, 3}, ids)
+
+	stmt, err = store.Update(BOOK).
+		Set(BOOK_C_PRICE, 10.0).
+		Where(BOOK_C_ID.Matches(1)).
+		ToSQL()
+	require.NoError(t, err)
+	// the price is bound as the translator binds decimals
+	price, err := store.GetTranslator().BindValue(BOOK_C_PRICE, 10.0)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{price, 1}, stmt.Args)
+
+	stmt, err = store.Delete(BOOK).
+		Where(BOOK_C_ID.Matches(2)).
+		ToSQL()
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{2}, stmt.Args)
+
+	stmt, err = store.Insert(PUBLISHER).
+		Columns(PUBLISHER_C_ID, PUBLISHER_C_VERSION, PUBLISHER_C_NAME).
+		Values(4, 1, "Dry Run").
+		ToSQL()
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{4, 1, "Dry Run"}, stmt.Args)
+}
+
+func (tt Tester) RunGenerics(t *testing.T) {
+	ResetDB(tt.Tm)
+
+	store := tt.Tm.Store()
+	books, err := db.List[*Book](store.Query(BOOK).All().Order(BOOK_C_ID))
+	require.NoError(t, err)
+	require.Len(t, books, 3)
+	require.Equal(t, int64(1), *books[0].Id)
+
+	values, err := db.List[Book](store.Query(BOOK).All().Where(BOOK_C_ID.Matches(2)))
+	require.NoError(t, err)
+	require.Len(t, values, 1)
+	require.Equal(t, "Cookbook", values[0].Name)
+
+	book, found, err := db.One[*Book](store.Query(BOOK).All().Order(BOOK_C_PRICE).Desc())
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, int64(1), *book.Id)
+
+	_, found, err = db.One[Book](store.Query(BOOK).All().Where(BOOK_C_ID.Matches(-1)))
+	require.NoError(t, err)
+	require.False(t, found)
+
+	book, found, err = db.Retrieve[*Book](store, 2)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, "Cookbook", book.Name)
+
+	names, err := db.ListInto[string](store.Query(BOOK).Column(BOOK_C_NAME).Order(BOOK_C_ID))
+	require.NoError(t, err)
+	require.Equal(t, []string{"Once Upon a Time...", "Cookbook", "Scrapbook"}, names)
+}
+
+func (tt Tester) RunIterate(t *testing.T) {
+	ResetDB(tt.Tm)
+
+	store := tt.Tm.Store()
+	cursor, err := store.Query(BOOK).
+		All().
+		Order(BOOK_C_ID).
+		Iterate()
+	require.NoError(t, err)
+
+	var ids []int64
+	var book Book
+	for cursor.Next() {
+		require.NoError(t, cursor.Scan(&book))
+		ids = append(ids, *book.Id)
+	}
+	require.NoError(t, cursor.Err())
+	require.NoError(t, cursor.Close())
+	require.Equal(t, []int64{1, 2, 3}, ids)
+	require.Equal(t, "Scrapbook", book.Name)
+
+	// pointers and scalars
+	cursor, err = store.Query(BOOK).
+		Column(BOOK_C_NAME).
+		Where(BOOK_C_ID.Matches(2)).
+		Iterate()
+	require.NoError(t, err)
+	defer cursor.Close()
+	require.True(t, cursor.Next())
+	var name string
+	require.NoError(t, cursor.Scan(&name))
+	require.Equal(t, "Cookbook", name)
+	require.False(t, cursor.Next())
+	require.NoError(t, cursor.Err())
+
+	cursor, err = store.Query(BOOK).
+		All().
+		Where(BOOK_C_ID.Matches(3)).
+		Iterate()
+	require.NoError(t, err)
+	defer cursor.Close()
+	require.True(t, cursor.Next())
+	var ptr *Book
+	require.NoError(t, cursor.Scan(&ptr))
+	require.Equal(t, "Scrapbook", ptr.Name)
+}
+
+func (tt Tester) RunNamingStrategy(t *testing.T) {
+	ResetDB(tt.Tm)
+
+	store := tt.Tm.Store()
+	// with the default naming only the fields with the column tag are matched
+	var book BookRow
+	ok, err := store.Query(BOOK).All().Where(BOOK_C_ID.Matches(1)).SelectTo(&book)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "Once Upon a Time...", *book.Title)
+	require.Nil(t, book.ID)
+	require.Nil(t, book.PublisherID)
+
+	mapper := db.NewTransactionManager(nil, store.GetTranslator(), db.TmWithNamingStrategy(db.SnakeCaseNaming))
+	snakeStore := db.NewDb(store.GetConnection(), store.GetTranslator(), mapper)
+	book = BookRow{}
+	ok, err = snakeStore.Query(BOOK).All().Where(BOOK_C_ID.Matches(1)).SelectTo(&book)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "Once Upon a Time...", *book.Title)
+	require.Equal(t, int64(1), *book.ID)
+	require.Equal(t, int64(1), *book.PublisherID)
+
+	// the marks are the field names, even when matched to other column aliases
+	mapper = db.NewTransactionManager(nil, store.GetTranslator(), db.TmWithNamingStrategy(db.PrefixNaming("BOOK_")))
+	prefixStore := db.NewDb(store.GetConnection(), store.GetTranslator(), mapper)
+	legacy := &LegacyBook{}
+	legacy.SetName("Legacy")
+	legacy.SetPrice(9.5)
+	_, err = prefixStore.Insert(LEGACY_BOOK).Submit(legacy)
+	require.NoError(t, err)
+	require.NotNil(t, legacy.Id)
+	require.Equal(t, int64(1), legacy.Version)
+
+	var saved Book
+	ok, err = store.Query(BOOK).All().Where(BOOK_C_ID.Matches(*legacy.Id)).SelectTo(&saved)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "Legacy", saved.Name)
+	require.Equal(t, 9.5, saved.Price)
+
+	legacy.SetPrice(11)
+	_, err = prefixStore.Update(LEGACY_BOOK).Submit(legacy)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), legacy.Version)
+
+	saved = Book{}
+	ok, err = store.Query(BOOK).All().Where(BOOK_C_ID.Matches(*legacy.Id)).SelectTo(&saved)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "Legacy", saved.Name)
+	require.Equal(t, float64(11), saved.Price)
+}
+
+func (tt Tester) RunSchema(t *testing.T) {
+	ResetDB(tt.Tm)
+
+	var dup *db.DuplicateAliasFail
+	require.ErrorAs(t, LABELS.TABLE("PUBLISHER").As("Label").Fault(), &dup)
+	require.Equal(t, "Label", dup.Alias)
+	require.Same(t, LABEL, LABELS.Table("Label"))
+
+	// the default schema does not know Label
+	store := tt.Tm.Store()
+	_, err := store.Retrieve(&Label{}, 1)
+	require.Error(t, err)
+
+	mapper := db.NewTransactionManager(nil, store.GetTranslator(), db.TmWithSchema(LABELS))
+	labelStore := db.NewDb(store.GetConnection(), store.GetTranslator(), mapper)
+	var label Label
+	ok, err := labelStore.Retrieve(&label, 1)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "Geek Publications", *label.Name)
+
+	label.Name = ext.String("Geek Labels")
+	_, err = labelStore.Modify(&label)
+	require.NoError(t, err)
+
+	var publisher Publisher
+	ok, err = store.Retrieve(&publisher, 1)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "Geek Labels", *publisher.Name)
+}
+
+func (tt Tester) RunIsNull(t *testing.T) {
+	ResetDB(tt.Tm)
+
+	store := tt.Tm.Store()
+	stmt, err := store.Query(PUBLISHER).Column(PUBLISHER_C_ID).Where(PUBLISHER_C_NAME.IsNull()).ToSQL()
+	require.NoError(t, err)
+	require.Contains(t, stmt.Sql, "NAME IS NULL")
+
+	stmt, err = store.Query(PUBLISHER).Column(PUBLISHER_C_ID).Where(PUBLISHER_C_NAME.IsNull().Not()).ToSQL()
+	require.NoError(t, err)
+	require.Contains(t, stmt.Sql, "NAME IS NOT NULL")
+
+	_, err = store.Update(PUBLISHER).Set(PUBLISHER_C_NAME, nil).Where(PUBLISHER_C_ID.Matches(1)).Execute()
+	require.NoError(t, err)
+
+	var count int64
+	_, err = store.Query(PUBLISHER).CountAll().Where(PUBLISHER_C_NAME.IsNull()).SelectInto(&count)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+
+	_, err = store.Query(PUBLISHER).CountAll().Where(PUBLISHER_C_NAME.IsNull().Not()).SelectInto(&count)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+}
+
+func (tt Tester) RunSoftDelete(t *testing.T) {
+	store := tt.Tm.Store()
+	// leftovers of a previous failed run
+	_ = db.DropTables(store, NOTE_LINE, NOTE)
+
+	err := db.CreateTables(store, NOTE, NOTE_LINE)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.DropTables(store, NOTE_LINE, NOTE))
+	}()
+
+	count := func(query *db.Query) int64 {
+		var c int64
+		_, err := query.SelectInto(&c)
+		require.NoError(t, err)
+		return c
+	}
+
+	// inserts do not set the deletion flag, and the rows with a NULL flag are not deleted
+	first := &Note{Title: ext.String("First")}
+	_, err = store.Insert(NOTE).Submit(first)
+	require.NoError(t, err)
+	second := &Note{Title: ext.String("Second")}
+	_, err = store.Insert(NOTE).Submit(second)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count(store.Query(NOTE).CountAll()))
+
+	lines := []*NoteLine{
+		{NoteId: first.Id, Content: ext.String("a")},
+		{NoteId: first.Id, Content: ext.String("b")},
+		{NoteId: second.Id, Content: ext.String("c")},
+	}
+	for _, line := range lines {
+		_, err = store.Insert(NOTE_LINE).Submit(line)
+		require.NoError(t, err)
+	}
+
+	// delete issues an update
+	stmt, err := store.Delete(NOTE).Where(NOTE_C_ID.Matches(*second.Id)).ToSQL()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(strings.TrimSpace(strings.ToUpper(stmt.Sql)), "UPDATE"), stmt.Sql)
+
+	affected, err := store.Delete(NOTE).Where(NOTE_C_ID.Matches(*second.Id)).Execute()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), affected)
+
+	var enabled int64
+	ok, err := store.Query(NOTE).IncludeDeleted().Column(NOTE_C_ENABLED).Where(NOTE_C_ID.Matches(*second.Id)).SelectInto(&enabled)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, int64(0), enabled)
+
+	// flagged rows are filtered out
+	require.Equal(t, int64(1), count(store.Query(NOTE).CountAll()))
+	ok, err = store.Retrieve(&Note{}, *second.Id)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// remove issues an update
+	ok, err = store.Remove(lines[1])
+	require.NoError(t, err)
+	require.True(t, ok)
+	var deleted *time.Time
+	ok, err = store.Query(NOTE_LINE).IncludeDeleted().Column(NOTE_LINE_C_DELETED).Where(NOTE_LINE_C_ID.Matches(*lines[1].Id)).SelectInto(&deleted)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NotNil(t, deleted)
+
+	var found []*NoteLine
+	err = store.FindAll(&found, NoteLine{NoteId: first.Id})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	require.Equal(t, "a", *found[0].Content)
+
+	// joined tables are filtered out in both directions
+	require.Equal(t, int64(1), count(store.Query(NOTE).CountAll().Inner(NOTE_A_LINES).Join()))
+	require.Equal(t, int64(1), count(store.Query(NOTE_LINE).CountAll().Inner(NOTE_LINE_A_NOTE).Join()))
+
+	// unless the deleted rows are included
+	require.Equal(t, int64(2), count(store.Query(NOTE).IncludeDeleted().CountAll()))
+	require.Equal(t, int64(3), count(store.Query(NOTE_LINE).IncludeDeleted().CountAll().Inner(NOTE_LINE_A_NOTE).Join()))
+
+	// too late to include the deleted rows
+	_, err = store.Query(NOTE).CountAll().Where(NOTE_C_ID.Matches(*second.Id)).IncludeDeleted().SelectInto(&enabled)
+	require.Error(t, err)
+	_, err = store.Query(NOTE_LINE).CountAll().Inner(NOTE_LINE_A_NOTE).Join().IncludeDeleted().SelectInto(&enabled)
+	require.Error(t, err)
+
+	// hard delete removes the rows
+	affected, err = store.Delete(NOTE_LINE).HardDelete().Where(NOTE_LINE_C_ID.Matches(*lines[1].Id)).Execute()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), affected)
+	require.Equal(t, int64(2), count(store.Query(NOTE_LINE).IncludeDeleted().CountAll()))
+}
+
+func (tt Tester) RunIdentityMap(t *testing.T) {
+	ResetDB(tt.Tm)
+
+	base := tt.Tm.Store()
+	tm := db.NewTransactionManager(base.GetConnection().(*sql.DB), base.GetTranslator(), db.TmWithIdentityMap())
+	load := func(store db.IDb) *Publisher {
+		var publishers []*Publisher
+		err := store.Query(PUBLISHER).All().Where(PUBLISHER_C_ID.Matches(1)).List(&publishers)
+		require.NoError(t, err)
+		require.Len(t, publishers, 1)
+		return publishers[0]
+	}
+
+	err := tm.Transaction(func(store db.IDb) error {
+		// the same instance is returned across queries
+		first := load(store)
+		require.Same(t, first, load(store))
+
+		cursor, err := store.Query(PUBLISHER).All().Where(PUBLISHER_C_ID.Matches(1)).Iterate()
+		require.NoError(t, err)
+		require.True(t, cursor.Next())
+		var scanned *Publisher
+		require.NoError(t, cursor.Scan(&scanned))
+		require.NoError(t, cursor.Close())
+		require.Same(t, first, scanned)
+
+		// retrieve is answered from the map, not seeing changes made behind its back
+		_, err = store.GetConnection().ExecContext(store.GetContext(), "UPDATE PUBLISHER SET NAME = 'Raw' WHERE ID = 1")
+		require.NoError(t, err)
+		var retrieved Publisher
+		ok, err := store.Retrieve(&retrieved, 1)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, "Geek Publications", *retrieved.Name)
+
+		// modify updates the instance in the map
+		retrieved.Name = ext.String("Modified")
+		ok, err = store.Modify(&retrieved)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, "Modified", *first.Name)
+
+		// create adds to the map and remove takes out of it
+		created := &Publisher{Name: ext.String("Temporary")}
+		require.NoError(t, store.Create(created))
+		var other Publisher
+		ok, err = store.Retrieve(&other, *created.Id)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, "Temporary", *other.Name)
+		ok, err = store.Remove(created)
+		require.NoError(t, err)
+		require.True(t, ok)
+		ok, err = store.Retrieve(&other, *created.Id)
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		// an update by criteria clears the map
+		_, err = store.Update(PUBLISHER).Set(PUBLISHER_C_NAME, "Updated").Where(PUBLISHER_C_ID.Matches(1)).Execute()
+		require.NoError(t, err)
+		updated := load(store)
+		require.NotSame(t, first, updated)
+		require.Equal(t, "Updated", *updated.Name)
+
+		// rolling back to a savepoint clears the map
+		err = tm.With(store).Transaction(func(store db.IDb) error {
+			return errors.New("nested failure")
+		})
+		require.Error(t, err)
+		require.NotSame(t, updated, load(store))
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -96,9 +96,9 @@ func (p *Publisher) HashCode() int {
 
 var (
 	PUBLISHER           = db.TABLE("PUBLISHER")
-	PUBLISHER_C_ID      = PUBLISHER.KEY("ID")          // implicit map to field Id
-	PUBLISHER_C_VERSION = PUBLISHER.VERSION("VERSION") // implicit map to field Version
-	PUBLISHER_C_NAME    = PUBLISHER.COLUMN("NAME")     // implicit map to field Name
+	PUBLISHER_C_ID      = PUBLISHER.KEY("ID")                                      // implicit map to field Id
+	PUBLISHER_C_VERSION = PUBLISHER.VERSION("VERSION")                             // implicit map to field Version
+	PUBLISHER_C_NAME    = PUBLISHER.COLUMN("NAME").Type(db.Varchar(50)).Nullable() // implicit map to field Name
 
 	PUBLISHER_A_BOOKS = PUBLISHER.
 				ASSOCIATE(PUBLISHER_C_ID).
@@ -156,7 +156,7 @@ var (
 	BOOK_BIN             = db.TABLE("BOOK_BIN")
 	BOOK_BIN_C_ID        = BOOK_BIN.KEY("ID")
 	BOOK_BIN_C_VERSION   = BOOK_BIN.VERSION("VERSION")
-	BOOK_BIN_C_HARDCOVER = BOOK_BIN.COLUMN("HARDCOVER")
+	BOOK_BIN_C_HARDCOVER = BOOK_BIN.COLUMN("HARDCOVER").Type(db.Binary()).Mandatory()
 
 	BOOK_BIN_A_BOOK = BOOK_BIN.
 			ASSOCIATE(BOOK_BIN_C_ID).
@@ -242,10 +242,10 @@ var (
 	BOOK                = db.TABLE("BOOK")
 	BOOK_C_ID           = BOOK.KEY("ID")
 	BOOK_C_VERSION      = BOOK.VERSION("VERSION")
-	BOOK_C_NAME         = BOOK.COLUMN("NAME")
-	BOOK_C_PRICE        = BOOK.COLUMN("PRICE")
-	BOOK_C_PUBLISHED    = BOOK.COLUMN("PUBLISHED")
-	BOOK_C_PUBLISHER_ID = BOOK.COLUMN("PUBLISHER_ID")
+	BOOK_C_NAME         = BOOK.COLUMN("NAME").Type(db.Varchar(100)).Nullable()
+	BOOK_C_PRICE        = BOOK.COLUMN("PRICE").Type(db.Decimal(18, 4)).Nullable()
+	BOOK_C_PUBLISHED    = BOOK.COLUMN("PUBLISHED").Type(db.Timestamp()).Nullable()
+	BOOK_C_PUBLISHER_ID = BOOK.COLUMN("PUBLISHER_ID").Type(db.Bigint()).Nullable()
 
 	BOOK_A_PUBLISHER = BOOK.
 				ASSOCIATE(BOOK_C_PUBLISHER_ID).
@@ -274,9 +274,9 @@ var (
 	BOOK_I18N           = db.TABLE("BOOK_I18N")
 	BOOK_I18N_C_ID      = BOOK_I18N.KEY("ID")
 	BOOK_I18N_C_VERSION = BOOK_I18N.VERSION("VERSION")
-	BOOK_I18N_C_BOOK_ID = BOOK_I18N.COLUMN("BOOK_ID")
-	BOOK_I18N_C_LANG    = BOOK_I18N.COLUMN("LANG")
-	BOOK_I18N_C_TITLE   = BOOK_I18N.COLUMN("TITLE")
+	BOOK_I18N_C_BOOK_ID = BOOK_I18N.COLUMN("BOOK_ID").Type(db.Bigint())
+	BOOK_I18N_C_LANG    = BOOK_I18N.COLUMN("LANG").Type(db.Varchar(10)).Nullable()
+	BOOK_I18N_C_TITLE   = BOOK_I18N.COLUMN("TITLE").Type(db.Varchar(100)).Nullable()
 )
 
 // AUTHOR_BOOK
@@ -338,8 +338,8 @@ var (
 	AUTHOR           = db.TABLE("AUTHOR")
 	AUTHOR_C_ID      = AUTHOR.KEY("ID")
 	AUTHOR_C_VERSION = AUTHOR.VERSION("VERSION")
-	AUTHOR_C_NAME    = AUTHOR.COLUMN("NAME")
-	AUTHOR_C_SECRET  = AUTHOR.COLUMN("SECRET")
+	AUTHOR_C_NAME    = AUTHOR.COLUMN("NAME").Type(db.Varchar(50)).Nullable()
+	AUTHOR_C_SECRET  = AUTHOR.COLUMN("SECRET").Type(db.Varchar(50)).Nullable()
 
 	AUTHOR_A_BOOKS = db.NewM2MAssociation(
 		"Books",
@@ -403,12 +403,12 @@ func (p *Project) HashCode() int {
 
 var (
 	PROJECT                = db.TABLE("PROJECT")
-	PROJECT_C_ID           = PROJECT.KEY("ID")              // implicit map to field Id
-	PROJECT_C_VERSION      = PROJECT.VERSION("VERSION")     // implicit map to field Version
-	PROJECT_C_NAME         = PROJECT.COLUMN("NAME")         // implicit map to field Name
-	PROJECT_C_MANAGER_ID   = PROJECT.COLUMN("MANAGER_ID")   // implicit map to field ManagerId
-	PROJECT_C_MANAGER_TYPE = PROJECT.COLUMN("MANAGER_TYPE") // implicit map to field ManagerType
-	PROJECT_C_STATUS       = PROJECT.COLUMN("STATUS_COD")   // implicit map to field Status
+	PROJECT_C_ID           = PROJECT.KEY("ID")                                            // implicit map to field Id
+	PROJECT_C_VERSION      = PROJECT.VERSION("VERSION")                                   // implicit map to field Version
+	PROJECT_C_NAME         = PROJECT.COLUMN("NAME").Type(db.Varchar(50)).Nullable()       // implicit map to field Name
+	PROJECT_C_MANAGER_ID   = PROJECT.COLUMN("MANAGER_ID").Type(db.Bigint())               // implicit map to field ManagerId
+	PROJECT_C_MANAGER_TYPE = PROJECT.COLUMN("MANAGER_TYPE").Type(db.Char(1))              // implicit map to field ManagerType
+	PROJECT_C_STATUS       = PROJECT.COLUMN("STATUS_COD").Type(db.Varchar(50)).Nullable() // implicit map to field Status
 
 	PROJECT_A_EMPLOYEE = PROJECT.
 				ASSOCIATE(PROJECT_C_MANAGER_ID).
@@ -475,10 +475,10 @@ func (p *Employee) HashCode() int {
 
 var (
 	EMPLOYEE              = db.TABLE("EMPLOYEE")
-	EMPLOYEE_C_ID         = EMPLOYEE.KEY("ID")            // implicit map to field Id
-	EMPLOYEE_C_VERSION    = EMPLOYEE.VERSION("VERSION")   // implicit map to field Version
-	EMPLOYEE_C_FIRST_NAME = EMPLOYEE.COLUMN("FIRST_NAME") // implicit map to field FirstName
-	EMPLOYEE_C_LAST_NAME  = EMPLOYEE.COLUMN("LAST_NAME")  // implicit map to field LastName
+	EMPLOYEE_C_ID         = EMPLOYEE.KEY("ID")                                            // implicit map to field Id
+	EMPLOYEE_C_VERSION    = EMPLOYEE.VERSION("VERSION")                                   // implicit map to field Version
+	EMPLOYEE_C_FIRST_NAME = EMPLOYEE.COLUMN("FIRST_NAME").Type(db.Varchar(50)).Nullable() // implicit map to field FirstName
+	EMPLOYEE_C_LAST_NAME  = EMPLOYEE.COLUMN("LAST_NAME").Type(db.Varchar(50)).Nullable()  // implicit map to field LastName
 
 	EMPLOYEE_A_PROJECT = EMPLOYEE.
 				ASSOCIATE(EMPLOYEE_C_ID).
@@ -532,9 +532,9 @@ func (c *Consultant) HashCode() int {
 
 var (
 	CONSULTANT           = db.TABLE("CONSULTANT")
-	CONSULTANT_C_ID      = CONSULTANT.KEY("ID")          // implicit map to field Id
-	CONSULTANT_C_VERSION = CONSULTANT.VERSION("VERSION") // implicit map to field Version
-	CONSULTANT_C_NAME    = CONSULTANT.COLUMN("NAME")     // implicit map to field Name
+	CONSULTANT_C_ID      = CONSULTANT.KEY("ID")                                      // implicit map to field Id
+	CONSULTANT_C_VERSION = CONSULTANT.VERSION("VERSION")                             // implicit map to field Version
+	CONSULTANT_C_NAME    = CONSULTANT.COLUMN("NAME").Type(db.Varchar(50)).Nullable() // implicit map to field Name
 
 	CONSULTANT_A_PROJECT = CONSULTANT.
 				ASSOCIATE(CONSULTANT_C_ID).
@@ -549,9 +549,9 @@ var (
 	CATALOG           = db.TABLE("CATALOG")
 	CATALOG_C_ID      = CATALOG.KEY("ID")          // implicit map to field Id
 	CATALOG_C_VERSION = CATALOG.VERSION("VERSION") // implicit map to field Version
-	CATALOG_C_DOMAIN  = CATALOG.COLUMN("DOMAIN")
-	CATALOG_C_CODE    = CATALOG.COLUMN("KEY")
-	CATALOG_C_VALUE   = CATALOG.COLUMN("VALUE")
+	CATALOG_C_DOMAIN  = CATALOG.COLUMN("DOMAIN").Type(db.Varchar(10)).Nullable()
+	CATALOG_C_CODE    = CATALOG.COLUMN("KEY").Type(db.Varchar(50)).Nullable()
+	CATALOG_C_VALUE   = CATALOG.COLUMN("VALUE").Type(db.Varchar(500)).Nullable()
 )
 
 // STATUS
@@ -683,3 +683,147 @@ type Supervisor2 struct {
 
 	FullName *FullNameVO `sql:"embedded"`
 }
+
+// GADGET and GADGET_PART are created by the DDL generated from the mappings
+
+type Gadget struct {
+	EntityBase
+
+	Name *string
+}
+
+var (
+	GADGET          = db.TABLE("GADGET")
+	GADGET_C_ID     = GADGET.KEY("ID")
+	GADGET_C_VER    = GADGET.VERSION("VERSION")
+	GADGET_C_NAME   = GADGET.COLUMN("NAME").Type(db.Varchar(50))
+	GADGET_C_PRICE  = GADGET.COLUMN("PRICE").Type(db.Decimal(10, 2)).Nullable()
+	GADGET_C_ACTIVE = GADGET.COLUMN("ACTIVE").Type(db.Boolean()).Default(true)
+	GADGET_C_MADE   = GADGET.COLUMN("MADE").Type(db.Timestamp()).Nullable()
+
+	_ = GADGET.UNIQUE("UK_GADGET_NAME", GADGET_C_NAME)
+)
+
+type GadgetPart struct {
+	EntityBase
+
+	GadgetId *int64
+	Name     *string
+}
+
+var (
+	GADGET_PART             = db.TABLE("GADGET_PART")
+	GADGET_PART_C_ID        = GADGET_PART.KEY("ID")
+	GADGET_PART_C_VER       = GADGET_PART.VERSION("VERSION")
+	GADGET_PART_C_GADGET_ID = GADGET_PART.COLUMN("GADGET_ID")
+	GADGET_PART_C_NAME      = GADGET_PART.COLUMN("NAME").Type(db.Varchar(50)).Nullable()
+
+	GADGET_PART_A_GADGET = GADGET_PART.
+				ASSOCIATE(GADGET_PART_C_GADGET_ID).
+				TO(GADGET_C_ID).
+				As("Gadget")
+
+	_ = GADGET_PART.INDEX("IX_GADGET_PART_GADGET", GADGET_PART_C_GADGET_ID)
+)
+
+// NOTE and NOTE_LINE are created by the DDL generated from the mappings, with soft delete
+
+type Note struct {
+	EntityBase
+
+	Title *string
+	Lines []*NoteLine
+}
+
+var (
+	NOTE           = db.TABLE("NOTE")
+	NOTE_C_ID      = NOTE.KEY("ID")
+	NOTE_C_VERSION = NOTE.VERSION("VERSION")
+	NOTE_C_TITLE   = NOTE.COLUMN("TITLE").Type(db.Varchar(50)).Nullable()
+	NOTE_C_ENABLED = NOTE.DELETION("ENABLED").DeletionFlag(0, 1)
+
+	NOTE_A_LINES = NOTE.
+			ASSOCIATE(NOTE_C_ID).
+			TO(NOTE_LINE_C_NOTE_ID).
+			As("Lines")
+)
+
+type NoteLine struct {
+	EntityBase
+
+	NoteId  *int64
+	Content *string
+	Note    *Note
+}
+
+var (
+	NOTE_LINE           = db.TABLE("NOTE_LINE")
+	NOTE_LINE_C_ID      = NOTE_LINE.KEY("ID")
+	NOTE_LINE_C_VERSION = NOTE_LINE.VERSION("VERSION")
+	NOTE_LINE_C_NOTE_ID = NOTE_LINE.COLUMN("NOTE_ID")
+	NOTE_LINE_C_CONTENT = NOTE_LINE.COLUMN("CONTENT").Type(db.Varchar(50)).Nullable()
+	NOTE_LINE_C_DELETED = NOTE_LINE.DELETION("DELETED")
+
+	NOTE_LINE_A_NOTE = NOTE_LINE.
+				ASSOCIATE(NOTE_LINE_C_NOTE_ID).
+				TO(NOTE_C_ID).
+				As("Note")
+)
+
+// BookRow maps BOOK without following the field naming of the column aliases
+
+type BookRow struct {
+	ID          *int64
+	Title       *string `sql:"column=NAME"`
+	PublisherID *int64
+}
+
+// LegacyBook maps BOOK with the column aliases of a legacy naming, prefixed by Book,
+// matched by PrefixNaming("BOOK_") and, for the price, by the col tag.
+// Only the changed fields, marked by the field name, are written.
+
+type LegacyBook struct {
+	db.Marker
+
+	Id      *int64
+	Version int64
+	Name    string
+	Price   float64 `sql:"col=Cost"`
+}
+
+func (b *LegacyBook) SetName(name string) {
+	b.Name = name
+	b.Mark("Name")
+}
+
+func (b *LegacyBook) SetPrice(price float64) {
+	b.Price = price
+	b.Mark("Price")
+}
+
+var (
+	LEGACY = db.NewSchema()
+
+	LEGACY_BOOK           = LEGACY.TABLE("BOOK").As("LegacyBook")
+	LEGACY_BOOK_C_ID      = LEGACY_BOOK.KEY("ID").As("BookId")
+	LEGACY_BOOK_C_VERSION = LEGACY_BOOK.VERSION("VERSION").As("BookVersion")
+	LEGACY_BOOK_C_NAME    = LEGACY_BOOK.COLUMN("NAME").As("BookName")
+	LEGACY_BOOK_C_PRICE   = LEGACY_BOOK.COLUMN("PRICE").As("Cost")
+)
+
+// LABEL maps PUBLISHER in a schema of its own, so it does not collide with the tables of the default schema
+
+type Label struct {
+	EntityBase
+
+	Name *string
+}
+
+var (
+	LABELS = db.NewSchema()
+
+	LABEL           = LABELS.TABLE("PUBLISHER").As("Label")
+	LABEL_C_ID      = LABEL.KEY("ID")
+	LABEL_C_VERSION = LABEL.VERSION("VERSION")
+	LABEL_C_NAME    = LABEL.COLUMN("NAME")
+)
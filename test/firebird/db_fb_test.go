@@ -88,3 +88,8 @@ func InitFirebirdSQL(port string) (ITransactionManager, *sql.DB, error) {
 		"tables_firebirdsql.sql",
 	)
 }
+
+// the mapping creates the same tables as the fixture
+func TestTablesFixture(t *testing.T) {
+	common.RequireTablesFixture(t, translators.NewFirebirdSQLTranslator(), "tables_firebirdsql.sql")
+}
@@ -10,6 +10,7 @@ import (
 	. "github.com/quintans/goSQL/db"
 	"github.com/quintans/goSQL/test/common"
 	"github.com/quintans/goSQL/translators"
+	"github.com/quintans/goSQL/translators/mysqlbulk"
 	"github.com/quintans/toolkit/log"
 )
 
@@ -79,6 +80,7 @@ func InitMySQL5(port string) (ITransactionManager, *sql.DB, error) {
 	common.RAW_SQL = "SELECT NAME FROM BOOK WHERE NAME LIKE ?"
 
 	translator := translators.NewMySQL5Translator()
+	translator.BulkLoader = mysqlbulk.LoadData
 	/*
 		registering custom function.
 		A custom translator could be created instead.
@@ -106,3 +108,8 @@ func InitMySQL5(port string) (ITransactionManager, *sql.DB, error) {
 		"tables_mysql.sql",
 	)
 }
+
+// the mapping creates the same tables as the fixture
+func TestTablesFixture(t *testing.T) {
+	common.RequireTablesFixture(t, translators.NewMySQL5Translator(), "tables_mysql.sql")
+}
@@ -2,7 +2,6 @@ CREATE TABLE `PUBLISHER` (
 	ID BIGINT NOT NULL AUTO_INCREMENT,
 	VERSION INTEGER NOT NULL,
 	`NAME` VARCHAR(50),
-	`ADDRESS` VARCHAR(255),
 	PRIMARY KEY(ID)
 )
 ENGINE=InnoDB
@@ -106,3 +106,8 @@ func InitOracle(port string) (ITransactionManager, *sql.DB, error) {
 		"tables_oracle.sql",
 	)
 }
+
+// the mapping creates the same tables as the fixture
+func TestTablesFixture(t *testing.T) {
+	common.RequireTablesFixture(t, translators.NewOracleTranslator(), "tables_oracle.sql")
+}
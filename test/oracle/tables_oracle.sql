@@ -2,7 +2,6 @@ CREATE TABLE "PUBLISHER" (
 	"ID" INTEGER NOT NULL,
 	"VERSION" INTEGER NOT NULL,
 	"NAME" VARCHAR2(50),
-	"ADDRESS" VARCHAR2(255),
 	PRIMARY KEY(ID)
 );
 
@@ -9,6 +9,7 @@ import (
 	. "github.com/quintans/goSQL/db"
 	"github.com/quintans/goSQL/test/common"
 	"github.com/quintans/goSQL/translators"
+	"github.com/quintans/goSQL/translators/pgbulk"
 	"github.com/quintans/toolkit/log"
 
 	_ "github.com/lib/pq"
@@ -79,6 +80,7 @@ func InitPostgreSQL(port string) (*TransactionManager, *sql.DB, error) {
 	common.RAW_SQL = "SELECT name FROM book WHERE name LIKE $1"
 
 	translator := translators.NewPostgreSQLTranslator()
+	translator.BulkLoader = pgbulk.CopyIn
 	translator.RegisterTranslation(
 		common.TOKEN_SECONDSDIFF,
 		func(dmlType DmlType, token Tokener, tx Translator) (string, error) {
@@ -102,3 +104,8 @@ func InitPostgreSQL(port string) (*TransactionManager, *sql.DB, error) {
 		"tables_postgresql.sql",
 	)
 }
+
+// the mapping creates the same tables as the fixture
+func TestTablesFixture(t *testing.T) {
+	common.RequireTablesFixture(t, translators.NewPostgreSQLTranslator(), "tables_postgresql.sql")
+}
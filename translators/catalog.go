@@ -0,0 +1,183 @@
+package translators
+
+import (
+	gosql "database/sql"
+	"strings"
+
+	"github.com/quintans/faults"
+	"github.com/quintans/goSQL/db"
+)
+
+// CatalogTranslator has the dialect specific parts of reading the database catalog, used by GenericTranslator
+type CatalogTranslator interface {
+	// CatalogSchemaSql returns the expression for the current schema, used by the information_schema queries
+	CatalogSchemaSql() string
+	// GetSqlForCatalogColumns returns the query for the columns of the tables, ordered by table and position,
+	// with the columns: table, column, data type, nullable (1 or 0), length, precision and scale
+	GetSqlForCatalogColumns() string
+	// GetSqlForCatalogKeys returns the query for the primary key columns,
+	// with the columns: table and column
+	GetSqlForCatalogKeys() string
+	// GetSqlForCatalogForeignKeys returns the query for the foreign key columns, ordered by table, constraint and position,
+	// with the columns: table, constraint, column, referenced table and referenced column
+	GetSqlForCatalogForeignKeys() string
+	// CatalogType returns the column type for a data type of the catalog
+	CatalogType(dataType string, length, precision, scale int) db.ColumnType
+}
+
+func (g *GenericTranslator) catalog() CatalogTranslator {
+	return g.overrider.(CatalogTranslator)
+}
+
+func (g *GenericTranslator) ReadCatalog(store db.IDb) ([]*db.CatalogTable, error) {
+	cat := g.catalog()
+	tables := []*db.CatalogTable{}
+	byName := map[string]*db.CatalogTable{}
+	tableOf := func(name string) *db.CatalogTable {
+		table := byName[name]
+		if table == nil {
+			table = &db.CatalogTable{Name: name}
+			byName[name] = table
+			tables = append(tables, table)
+		}
+		return table
+	}
+
+	err := queryCatalog(store, cat.GetSqlForCatalogColumns(), func(rows *gosql.Rows) error {
+		var tableName, name, dataType string
+		var nullable int64
+		var length, precision, scale gosql.NullInt64
+		if err := rows.Scan(&tableName, &name, &dataType, &nullable, &length, &precision, &scale); err != nil {
+			return err
+		}
+		table := tableOf(tableName)
+		table.Columns = append(table.Columns, &db.CatalogColumn{
+			Name:     name,
+			DataType: dataType,
+			Type:     cat.CatalogType(dataType, int(length.Int64), int(precision.Int64), int(scale.Int64)),
+			Nullable: nullable == 1,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = queryCatalog(store, cat.GetSqlForCatalogKeys(), func(rows *gosql.Rows) error {
+		var tableName, name string
+		if err := rows.Scan(&tableName, &name); err != nil {
+			return err
+		}
+		if table := byName[tableName]; table != nil {
+			table.Keys = append(table.Keys, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var last *db.CatalogForeignKey
+	var lastTable string
+	err = queryCatalog(store, cat.GetSqlForCatalogForeignKeys(), func(rows *gosql.Rows) error {
+		var tableName, constraint, name, tableTo, nameTo string
+		if err := rows.Scan(&tableName, &constraint, &name, &tableTo, &nameTo); err != nil {
+			return err
+		}
+		table := byName[tableName]
+		if table == nil {
+			return nil
+		}
+		if last == nil || last.Name != constraint || lastTable != tableName {
+			last = &db.CatalogForeignKey{Name: constraint, TableTo: tableTo}
+			lastTable = tableName
+			table.ForeignKeys = append(table.ForeignKeys, last)
+		}
+		last.Columns = append(last.Columns, name)
+		last.ColumnsTo = append(last.ColumnsTo, nameTo)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tables, nil
+}
+
+func queryCatalog(store db.IDb, sql string, handler func(rows *gosql.Rows) error) error {
+	rows, err := store.GetConnection().QueryContext(store.GetContext(), sql)
+	if err != nil {
+		return faults.Errorf("executing query\nSQL: %s: %w", sql, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := handler(rows); err != nil {
+			return faults.Wrap(err)
+		}
+	}
+	return faults.Wrap(rows.Err())
+}
+
+func (g *GenericTranslator) CatalogSchemaSql() string {
+	return "current_schema()"
+}
+
+func (g *GenericTranslator) GetSqlForCatalogColumns() string {
+	return "SELECT table_name, column_name, data_type, CASE WHEN is_nullable = 'YES' THEN 1 ELSE 0 END," +
+		" character_maximum_length, numeric_precision, numeric_scale" +
+		" FROM information_schema.columns" +
+		" WHERE table_schema = " + g.catalog().CatalogSchemaSql() +
+		" ORDER BY table_name, ordinal_position"
+}
+
+func (g *GenericTranslator) GetSqlForCatalogKeys() string {
+	return "SELECT kcu.table_name, kcu.column_name" +
+		" FROM information_schema.table_constraints tc" +
+		" JOIN information_schema.key_column_usage kcu ON kcu.constraint_schema = tc.constraint_schema" +
+		" AND kcu.constraint_name = tc.constraint_name AND kcu.table_name = tc.table_name" +
+		" WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = " + g.catalog().CatalogSchemaSql() +
+		" ORDER BY kcu.table_name, kcu.ordinal_position"
+}
+
+func (g *GenericTranslator) GetSqlForCatalogForeignKeys() string {
+	return "SELECT kcu.table_name, kcu.constraint_name, kcu.column_name, ref.table_name, ref.column_name" +
+		" FROM information_schema.referential_constraints rc" +
+		" JOIN information_schema.key_column_usage kcu ON kcu.constraint_schema = rc.constraint_schema" +
+		" AND kcu.constraint_name = rc.constraint_name" +
+		" JOIN information_schema.key_column_usage ref ON ref.constraint_schema = rc.unique_constraint_schema" +
+		" AND ref.constraint_name = rc.unique_constraint_name AND ref.ordinal_position = kcu.position_in_unique_constraint" +
+		" WHERE kcu.table_schema = " + g.catalog().CatalogSchemaSql() +
+		" ORDER BY kcu.table_name, kcu.constraint_name, kcu.ordinal_position"
+}
+
+func (g *GenericTranslator) CatalogType(dataType string, length, precision, scale int) db.ColumnType {
+	typ := strings.ToLower(dataType)
+	if strings.HasPrefix(typ, "timestamp") || typ == "datetime" {
+		return db.Timestamp()
+	}
+	switch typ {
+	case "character varying", "varchar", "varchar2", "nvarchar", "nvarchar2":
+		return db.Varchar(length)
+	case "character", "char", "nchar", "bpchar":
+		return db.Char(length)
+	case "text", "tinytext", "mediumtext", "longtext", "clob", "nclob", "blob sub_type text":
+		return db.Text()
+	case "smallint", "tinyint", "int2":
+		return db.Smallint()
+	case "integer", "int", "int4", "mediumint":
+		return db.Integer()
+	case "bigint", "int8":
+		return db.Bigint()
+	case "numeric", "decimal", "number":
+		return db.Decimal(precision, scale)
+	case "double precision", "double", "float", "float4", "float8", "real", "binary_double", "binary_float":
+		return db.Double()
+	case "boolean", "bool":
+		return db.Boolean()
+	case "date":
+		return db.Date()
+	case "bytea", "blob", "tinyblob", "mediumblob", "longblob", "binary", "varbinary", "raw":
+		return db.Binary()
+	}
+	return db.ColumnType{Kind: db.TYPE_UNKNOWN}
+}
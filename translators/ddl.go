@@ -0,0 +1,264 @@
+package translators
+
+import (
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/quintans/goSQL/db"
+	tk "github.com/quintans/toolkit"
+)
+
+// DdlTranslator has the dialect specific parts of the DDL generated by GenericTranslator
+type DdlTranslator interface {
+	// ColumnTypeSql returns the database type of the column
+	ColumnTypeSql(column *db.Column) string
+	// AutoKeySql returns the definition of a generated key column, without the NOT NULL,
+	// or an empty string if the key is not generated by the column definition
+	AutoKeySql(column *db.Column) string
+	// TableOptionsSql returns what follows the closing parenthesis of CREATE TABLE
+	TableOptionsSql(table *db.Table) string
+	// GetSqlForCreateAutoKey returns the statements creating what generates the key of the table, like sequences
+	GetSqlForCreateAutoKey(table *db.Table) []string
+	// GetSqlForDropAutoKey returns the statements dropping what GetSqlForCreateAutoKey creates
+	GetSqlForDropAutoKey(table *db.Table) []string
+	// GetSqlForDropForeignKey returns the statement dropping a foreign key
+	GetSqlForDropForeignKey(fk *db.ForeignKey) string
+}
+
+// maxConstraintName is the maximum length of the generated constraint names, accepted by all dialects
+const maxConstraintName = 30
+
+// ddlType returns the declared type of the column, or the type given by its role, if none was declared.
+// Keys are BIGINT, versions are INTEGER, deletion timestamps are TIMESTAMP, deletion flags are SMALLINT,
+// columns referencing a key have the type of the key and any other column is VARCHAR(255).
+func ddlType(column *db.Column) db.ColumnType {
+	if typ := column.GetType(); typ != nil {
+		return *typ
+	}
+	switch {
+	case column.IsKey():
+		if ref := db.ReferencedColumn(column); ref != nil && ref.GetType() != nil {
+			return *ref.GetType()
+		}
+		return db.Bigint()
+	case column.IsVersion():
+		return db.Integer()
+	case column.IsDeletion():
+		if column.IsDeletionFlag() {
+			return db.Smallint()
+		}
+		return db.Timestamp()
+	default:
+		if ref := db.ReferencedColumn(column); ref != nil {
+			return ddlType(ref)
+		}
+		return db.Varchar(255)
+	}
+}
+
+// isAutoKey returns true if the column is the single key of its table, with an integer type
+func isAutoKey(column *db.Column) bool {
+	if column.GetTable().GetSingleKeyColumn() != column {
+		return false
+	}
+	switch ddlType(column).Kind {
+	case db.TYPE_SMALLINT, db.TYPE_INTEGER, db.TYPE_BIGINT:
+		return true
+	}
+	return false
+}
+
+func isNotNull(column *db.Column) bool {
+	if column.IsKey() || column.IsVersion() || column.IsMandatory() {
+		return true
+	}
+	return column.GetType() != nil && !column.IsNullable()
+}
+
+// constraintName returns the name in upper case.
+// A name longer than maxConstraintName is truncated and ends with the hash of the full name,
+// so that names with the same beginning do not collide.
+func constraintName(name string) string {
+	name = strings.ToUpper(name)
+	if len(name) > maxConstraintName {
+		h := fnv.New32a()
+		h.Write([]byte(name))
+		suffix := fmt.Sprintf("_%08X", h.Sum32())
+		name = name[:maxConstraintName-len(suffix)] + suffix
+	}
+	return name
+}
+
+func (g *GenericTranslator) ddl() DdlTranslator {
+	return g.overrider.(DdlTranslator)
+}
+
+// uniqueTables removes the logical tables mapped to an already present physical table
+func uniqueTables(tables []*db.Table) []*db.Table {
+	names := map[string]bool{}
+	list := []*db.Table{}
+	for _, table := range tables {
+		name := strings.ToUpper(table.GetName())
+		if !names[name] {
+			names[name] = true
+			list = append(list, table)
+		}
+	}
+	return list
+}
+
+func (g *GenericTranslator) GetSqlForCreate(tables []*db.Table) []string {
+	tables = uniqueTables(tables)
+	ddl := g.ddl()
+	stmts := []string{}
+	for _, table := range tables {
+		stmts = append(stmts, ddl.GetSqlForCreateAutoKey(table)...)
+		stmts = append(stmts, g.GetSqlForCreateTable(table))
+		for _, index := range table.GetIndexes() {
+			stmts = append(stmts, g.GetSqlForCreateIndex(table, index))
+		}
+	}
+	for _, fk := range db.ForeignKeys(tables) {
+		stmts = append(stmts, g.GetSqlForCreateForeignKey(fk))
+	}
+	return stmts
+}
+
+func (g *GenericTranslator) GetSqlForDrop(tables []*db.Table) []string {
+	tables = uniqueTables(tables)
+	ddl := g.ddl()
+	stmts := []string{}
+	for _, fk := range db.ForeignKeys(tables) {
+		stmts = append(stmts, ddl.GetSqlForDropForeignKey(fk))
+	}
+	for _, table := range tables {
+		stmts = append(stmts, "DROP TABLE "+g.overrider.TableName(table))
+		stmts = append(stmts, ddl.GetSqlForDropAutoKey(table)...)
+	}
+	return stmts
+}
+
+func (g *GenericTranslator) GetSqlForCreateTable(table *db.Table) string {
+	ddl := g.ddl()
+	defs := tk.NewJoiner(",\n\t")
+	for e := table.GetColumns().Enumerator(); e.HasNext(); {
+		defs.Add(g.ColumnDefinitionSql(e.Next().(*db.Column)))
+	}
+
+	if table.GetKeyColumns().Size() > 0 {
+		keys := tk.NewJoiner(", ")
+		for e := table.GetKeyColumns().Enumerator(); e.HasNext(); {
+			keys.Add(g.overrider.ColumnName(e.Next().(*db.Column)))
+		}
+		defs.Add("CONSTRAINT " + constraintName("PK_"+table.GetName()) + " PRIMARY KEY (" + keys.String() + ")")
+	}
+
+	return "CREATE TABLE " + g.overrider.TableName(table) + " (\n\t" + defs.String() + "\n)" + ddl.TableOptionsSql(table)
+}
+
+// ColumnDefinitionSql returns the definition of the column, in CREATE TABLE
+func (g *GenericTranslator) ColumnDefinitionSql(column *db.Column) string {
+	ddl := g.ddl()
+	sb := tk.NewStrBuffer(g.overrider.ColumnName(column), " ")
+	if auto := ddl.AutoKeySql(column); auto != "" && isAutoKey(column) {
+		sb.Add(auto)
+	} else {
+		sb.Add(ddl.ColumnTypeSql(column))
+	}
+	if column.HasDefault() {
+		sb.Add(" DEFAULT ", g.DefaultSql(column))
+	}
+	if isNotNull(column) {
+		sb.Add(" NOT NULL")
+	}
+	return sb.String()
+}
+
+// DefaultSql returns the literal of the default value of the column, bound as a parameter would be
+func (g *GenericTranslator) DefaultSql(column *db.Column) string {
+	value, err := g.overrider.BindValue(column, column.GetDefault())
+	if err != nil {
+		value = column.GetDefault()
+	}
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case time.Time:
+		return "'" + v.Format("2006-01-02 15:04:05") + "'"
+	}
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Ptr && !v.IsNil() {
+		value = v.Elem().Interface()
+	}
+	return fmt.Sprint(value)
+}
+
+func (g *GenericTranslator) GetSqlForCreateIndex(table *db.Table, index *db.Index) string {
+	cols := tk.NewJoiner(", ")
+	for _, c := range index.Columns {
+		cols.Add(g.overrider.ColumnName(c))
+	}
+	unique := ""
+	if index.Unique {
+		unique = "UNIQUE "
+	}
+	return "CREATE " + unique + "INDEX " + constraintName(index.Name) + " ON " + g.overrider.TableName(table) + " (" + cols.String() + ")"
+}
+
+func (g *GenericTranslator) GetSqlForCreateForeignKey(fk *db.ForeignKey) string {
+	cols := tk.NewJoiner(", ")
+	for _, c := range fk.Columns {
+		cols.Add(g.overrider.ColumnName(c))
+	}
+	refs := tk.NewJoiner(", ")
+	for _, c := range fk.ColumnsTo {
+		refs.Add(g.overrider.ColumnName(c))
+	}
+	return "ALTER TABLE " + g.overrider.TableName(fk.Table) +
+		" ADD CONSTRAINT " + constraintName(fk.Name) +
+		" FOREIGN KEY (" + cols.String() + ") REFERENCES " + g.overrider.TableName(fk.TableTo) + " (" + refs.String() + ")"
+}
+
+// ColumnTypeSql returns the standard SQL type of the column
+func (g *GenericTranslator) ColumnTypeSql(column *db.Column) string {
+	typ := ddlType(column)
+	switch typ.Kind {
+	case db.TYPE_TEXT:
+		return "CLOB"
+	case db.TYPE_DOUBLE:
+		return "DOUBLE PRECISION"
+	case db.TYPE_BINARY:
+		return "BLOB"
+	}
+	return typ.String()
+}
+
+// AutoKeySql returns an empty string. Keys are not generated by the column definition.
+func (g *GenericTranslator) AutoKeySql(column *db.Column) string {
+	return ""
+}
+
+func (g *GenericTranslator) TableOptionsSql(table *db.Table) string {
+	return ""
+}
+
+func (g *GenericTranslator) GetSqlForCreateAutoKey(table *db.Table) []string {
+	return nil
+}
+
+func (g *GenericTranslator) GetSqlForDropAutoKey(table *db.Table) []string {
+	return nil
+}
+
+func (g *GenericTranslator) GetSqlForDropForeignKey(fk *db.ForeignKey) string {
+	return "ALTER TABLE " + g.overrider.TableName(fk.Table) + " DROP CONSTRAINT " + constraintName(fk.Name)
+}
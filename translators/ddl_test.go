@@ -0,0 +1,23 @@
+package translators
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstraintName(t *testing.T) {
+	require.Equal(t, "FK_BOOK_PUBLISHER", constraintName("fk_book_publisher"))
+
+	exact := "FK_ABCDEFGHIJKLMNOPQRSTUVWXYZ1"
+	require.Equal(t, exact, constraintName(exact))
+
+	first := constraintName("FK_PUBLISHER_BOOK_REVIEW_AUTHOR_ID")
+	second := constraintName("FK_PUBLISHER_BOOK_REVIEW_AUTHOR_NAME")
+	require.Len(t, first, maxConstraintName)
+	require.Len(t, second, maxConstraintName)
+	require.NotEqual(t, first, second)
+	require.Equal(t, "FK_PUBLISHER_BOOK_REV", first[:21])
+	// the same name always gives the same constraint
+	require.Equal(t, first, constraintName("fk_publisher_book_review_author_id"))
+}
@@ -7,11 +7,16 @@ import (
 	tk "github.com/quintans/toolkit"
 )
 
+const FIREBIRD_DIALECT = "Firebird"
+
 type FirebirdSQLTranslator struct {
 	*GenericTranslator
 }
 
-var _ db.Translator = &FirebirdSQLTranslator{}
+var (
+	_ db.Translator   = &FirebirdSQLTranslator{}
+	_ db.QueryChecker = &FirebirdSQLTranslator{}
+)
 
 func NewFirebirdSQLTranslator() *FirebirdSQLTranslator {
 	this := new(FirebirdSQLTranslator)
@@ -24,6 +29,11 @@ func NewFirebirdSQLTranslator() *FirebirdSQLTranslator {
 	return this
 }
 
+// CheckQuery rejects the features that Firebird does not support: ROLLUP, CUBE, GROUPING SETS and the GROUPING function
+func (f *FirebirdSQLTranslator) CheckQuery(query *db.Query) error {
+	return checkGrouping(FIREBIRD_DIALECT, query, db.GROUPING_ROLLUP, db.GROUPING_CUBE, db.GROUPING_SETS)
+}
+
 func (f *FirebirdSQLTranslator) GetAutoKeyStrategy() db.AutoKeyStrategy {
 	// we could use autoincrement but for test purposes we are using sequences
 	return db.AUTOKEY_BEFORE
@@ -33,6 +43,11 @@ func (f *FirebirdSQLTranslator) GetAutoNumberQuery(column *db.Column) string {
 	return "select GEN_ID(" + column.GetTable().GetName() + "_GEN, 1) from RDB$DATABASE"
 }
 
+// BindValue binds floating point values of DECIMAL columns as strings
+func (f *FirebirdSQLTranslator) BindValue(column *db.Column, value interface{}) (interface{}, error) {
+	return decimalValue(column, value), nil
+}
+
 // INSERT
 // 2013-06-15: available odbc drivers do not implement RETURNING
 
@@ -60,3 +75,70 @@ func (f *FirebirdSQLTranslator) PaginateSQL(query *db.Query, sql string) string
 
 	return sql
 }
+
+// DDL
+
+func (f *FirebirdSQLTranslator) ColumnTypeSql(column *db.Column) string {
+	if ddlType(column).Kind == db.TYPE_TEXT {
+		return "BLOB SUB_TYPE TEXT"
+	}
+	return f.GenericTranslator.ColumnTypeSql(column)
+}
+
+// GetSqlForCreateAutoKey creates the generator used by GetAutoNumberQuery
+func (f *FirebirdSQLTranslator) GetSqlForCreateAutoKey(table *db.Table) []string {
+	if column := table.GetSingleKeyColumn(); column != nil && isAutoKey(column) {
+		return []string{"CREATE SEQUENCE " + strings.ToUpper(table.GetName()) + "_GEN"}
+	}
+	return nil
+}
+
+func (f *FirebirdSQLTranslator) GetSqlForDropAutoKey(table *db.Table) []string {
+	if column := table.GetSingleKeyColumn(); column != nil && isAutoKey(column) {
+		return []string{"DROP SEQUENCE " + strings.ToUpper(table.GetName()) + "_GEN"}
+	}
+	return nil
+}
+
+// CATALOG
+
+// GetSqlForCatalogColumns names the types of RDB$FIELDS. Integer types with a negative scale are DECIMAL.
+func (f *FirebirdSQLTranslator) GetSqlForCatalogColumns() string {
+	return "SELECT TRIM(rf.RDB$RELATION_NAME), TRIM(rf.RDB$FIELD_NAME)," +
+		" CASE fd.RDB$FIELD_TYPE" +
+		" WHEN 7 THEN CASE WHEN fd.RDB$FIELD_SCALE < 0 THEN 'DECIMAL' ELSE 'SMALLINT' END" +
+		" WHEN 8 THEN CASE WHEN fd.RDB$FIELD_SCALE < 0 THEN 'DECIMAL' ELSE 'INTEGER' END" +
+		" WHEN 16 THEN CASE WHEN fd.RDB$FIELD_SCALE < 0 THEN 'DECIMAL' ELSE 'BIGINT' END" +
+		" WHEN 10 THEN 'FLOAT' WHEN 27 THEN 'DOUBLE PRECISION'" +
+		" WHEN 12 THEN 'DATE' WHEN 13 THEN 'TIME' WHEN 35 THEN 'TIMESTAMP'" +
+		" WHEN 14 THEN 'CHAR' WHEN 37 THEN 'VARCHAR' WHEN 23 THEN 'BOOLEAN'" +
+		" WHEN 261 THEN CASE fd.RDB$FIELD_SUB_TYPE WHEN 1 THEN 'BLOB SUB_TYPE TEXT' ELSE 'BLOB' END" +
+		" ELSE 'UNKNOWN' END," +
+		" CASE WHEN COALESCE(rf.RDB$NULL_FLAG, fd.RDB$NULL_FLAG, 0) = 1 THEN 0 ELSE 1 END," +
+		" fd.RDB$CHARACTER_LENGTH, fd.RDB$FIELD_PRECISION, -fd.RDB$FIELD_SCALE" +
+		" FROM RDB$RELATION_FIELDS rf" +
+		" JOIN RDB$FIELDS fd ON fd.RDB$FIELD_NAME = rf.RDB$FIELD_SOURCE" +
+		" JOIN RDB$RELATIONS r ON r.RDB$RELATION_NAME = rf.RDB$RELATION_NAME" +
+		" WHERE COALESCE(r.RDB$SYSTEM_FLAG, 0) = 0 AND r.RDB$VIEW_BLR IS NULL" +
+		" ORDER BY rf.RDB$RELATION_NAME, rf.RDB$FIELD_POSITION"
+}
+
+func (f *FirebirdSQLTranslator) GetSqlForCatalogKeys() string {
+	return "SELECT TRIM(rc.RDB$RELATION_NAME), TRIM(s.RDB$FIELD_NAME)" +
+		" FROM RDB$RELATION_CONSTRAINTS rc" +
+		" JOIN RDB$INDEX_SEGMENTS s ON s.RDB$INDEX_NAME = rc.RDB$INDEX_NAME" +
+		" WHERE rc.RDB$CONSTRAINT_TYPE = 'PRIMARY KEY'" +
+		" ORDER BY rc.RDB$RELATION_NAME, s.RDB$FIELD_POSITION"
+}
+
+func (f *FirebirdSQLTranslator) GetSqlForCatalogForeignKeys() string {
+	return "SELECT TRIM(rc.RDB$RELATION_NAME), TRIM(rc.RDB$CONSTRAINT_NAME), TRIM(s.RDB$FIELD_NAME)," +
+		" TRIM(uq.RDB$RELATION_NAME), TRIM(us.RDB$FIELD_NAME)" +
+		" FROM RDB$RELATION_CONSTRAINTS rc" +
+		" JOIN RDB$REF_CONSTRAINTS ref ON ref.RDB$CONSTRAINT_NAME = rc.RDB$CONSTRAINT_NAME" +
+		" JOIN RDB$RELATION_CONSTRAINTS uq ON uq.RDB$CONSTRAINT_NAME = ref.RDB$CONST_NAME_UQ" +
+		" JOIN RDB$INDEX_SEGMENTS s ON s.RDB$INDEX_NAME = rc.RDB$INDEX_NAME" +
+		" JOIN RDB$INDEX_SEGMENTS us ON us.RDB$INDEX_NAME = uq.RDB$INDEX_NAME AND us.RDB$FIELD_POSITION = s.RDB$FIELD_POSITION" +
+		" WHERE rc.RDB$CONSTRAINT_TYPE = 'FOREIGN KEY'" +
+		" ORDER BY rc.RDB$RELATION_NAME, rc.RDB$CONSTRAINT_NAME, s.RDB$FIELD_POSITION"
+}
@@ -1,10 +1,13 @@
 package translators
 
 import (
+	"context"
+	gosql "database/sql"
 	"strings"
 
 	"github.com/quintans/faults"
 	"github.com/quintans/goSQL/db"
+	"github.com/quintans/goSQL/dbx"
 	tk "github.com/quintans/toolkit"
 
 	"fmt"
@@ -194,18 +197,53 @@ func (q *QueryBuilder) Where(query *db.Query) error {
 }
 
 func (q *QueryBuilder) Group(query *db.Query) error {
-	groups := query.GetGroupByTokens()
-	for _, group := range groups {
-		//this.groupPart.Add(this.translator.ColumnAlias(group.Token, group.Position))
-		s, err := q.translator.Translate(db.QUERY, group.Token)
+	switch query.GetGrouping() {
+	case db.GROUPING_ROLLUP, db.GROUPING_CUBE:
+		args, err := q.groupTokens(query.GetGroupByTokens())
+		if err != nil {
+			return faults.Wrap(err)
+		}
+		fn := "ROLLUP("
+		if query.GetGrouping() == db.GROUPING_CUBE {
+			fn = "CUBE("
+		}
+		q.groupPart.Add(fn + strings.Join(args, ", ") + ")")
+	case db.GROUPING_SETS:
+		sets := query.GetGroupingSetsTokens()
+		parts := make([]string, len(sets))
+		for k, set := range sets {
+			args, err := q.groupTokens(set)
+			if err != nil {
+				return faults.Wrap(err)
+			}
+			parts[k] = "(" + strings.Join(args, ", ") + ")"
+		}
+		q.groupPart.Add("GROUPING SETS (" + strings.Join(parts, ", ") + ")")
+	default:
+		args, err := q.groupTokens(query.GetGroupByTokens())
 		if err != nil {
 			return faults.Wrap(err)
 		}
-		q.groupPart.Add(s)
+		for _, s := range args {
+			q.groupPart.Add(s)
+		}
 	}
 	return nil
 }
 
+func (q *QueryBuilder) groupTokens(groups []db.Group) ([]string, error) {
+	args := make([]string, len(groups))
+	for k, group := range groups {
+		//this.groupPart.Add(this.translator.ColumnAlias(group.Token, group.Position))
+		s, err := q.translator.Translate(db.QUERY, group.Token)
+		if err != nil {
+			return nil, faults.Wrap(err)
+		}
+		args[k] = s
+	}
+	return args, nil
+}
+
 func (q *QueryBuilder) Having(query *db.Query) error {
 	having := query.GetHaving()
 	if having != nil {
@@ -502,7 +540,10 @@ type GenericTranslator struct {
 	InsertProcessorFactory func() InsertProcessor
 	UpdateProcessorFactory func() UpdateProcessor
 	DeleteProcessorFactory func() DeleteProcessor
-	converters             map[string]db.Converter
+	// BulkLoader is the native bulk path of the database driver, like the ones of pgbulk and mysqlbulk.
+	// If nil, BulkLoad inserts the rows one by one.
+	BulkLoader BulkLoader
+	converters map[string]db.Converter
 }
 
 func (g *GenericTranslator) Init(overrider db.Translator) {
@@ -742,7 +783,7 @@ func (g *GenericTranslator) Init(overrider db.Translator) {
 		if err != nil {
 			return "", faults.Wrap(err)
 		}
-		sb := tk.NewStrBuffer(args[0], "IS", isNot(c), " NULL")
+		sb := tk.NewStrBuffer(args[0], " IS", isNot(c), " NULL")
 		return sb.String(), nil
 	})
 
@@ -903,6 +944,16 @@ func (g *GenericTranslator) Init(overrider db.Translator) {
 		return strings.Join(args, " * "), nil
 	})
 
+	g.RegisterTranslation(db.TOKEN_GROUPING, func(dmlType db.DmlType, token db.Tokener, tx db.Translator) (string, error) {
+		m := token.GetMembers()
+		args, err := Translate(tx.Translate, dmlType, m...)
+		if err != nil {
+			return "", faults.Wrap(err)
+		}
+		sb := tk.NewStrBuffer("GROUPING(", args[0], ")")
+		return sb.String(), nil
+	})
+
 	g.RegisterTranslation(db.TOKEN_COUNT, func(dmlType db.DmlType, token db.Tokener, tx db.Translator) (string, error) {
 		return "COUNT(*)", nil
 	})
@@ -930,7 +981,7 @@ func (g *GenericTranslator) Init(overrider db.Translator) {
 	g.RegisterTranslation(db.TOKEN_SUBQUERY, func(dmlType db.DmlType, token db.Tokener, tx db.Translator) (string, error) {
 		v := token.GetValue()
 		query := v.(*db.Query)
-		return fmt.Sprintf("( %s )", g.GetSqlForQuery(query)), nil
+		return fmt.Sprintf("( %s )", tx.GetSqlForQuery(query)), nil
 	})
 
 	g.RegisterTranslation(db.TOKEN_COALESCE, func(dmlType db.DmlType, token db.Tokener, tx db.Translator) (string, error) {
@@ -1081,6 +1132,20 @@ func (g *GenericTranslator) GetSqlForDelete(del *db.Delete) string {
 	return sb.String()
 }
 
+// SAVEPOINTS
+
+func (g *GenericTranslator) GetSqlForSavepoint(name string) string {
+	return "SAVEPOINT " + name
+}
+
+func (g *GenericTranslator) GetSqlForRollbackTo(name string) string {
+	return "ROLLBACK TO SAVEPOINT " + name
+}
+
+func (g *GenericTranslator) GetSqlForRelease(name string) string {
+	return "RELEASE SAVEPOINT " + name
+}
+
 //	@Override
 //	func (this *GenericTranslator) String getSql(Sequence sequence, boolean nextValue) {
 //		throw new UnsupportedOperationException();
@@ -1110,13 +1175,113 @@ func (g *GenericTranslator) CreateQueryProcessor(query *db.Query) QueryProcessor
 func (g *GenericTranslator) GetSqlForQuery(query *db.Query) string {
 	proc := g.CreateQueryProcessor(query)
 
-	// SELECT COLUNAS
+	var sql string
+	if len(query.GetDistinctOn()) != 0 {
+		sql = g.distinctOnSql(query, proc)
+	} else {
+		columns := proc.ColumnPart()
+		if query.IsDistinct() {
+			columns = "DISTINCT " + columns
+		}
+		sql = SelectSql(query, proc, columns, proc.OrderPart())
+	}
+
+	return g.overrider.PaginateSQL(query, sql)
+}
+
+const (
+	DISTINCT_ON_ALIAS     = "dst"
+	DISTINCT_ON_ROW_ALIAS = "DST_ROW"
+	DISTINCT_ON_ORD_ALIAS = "DST_ORD_"
+)
+
+// distinctOnSql emulates DISTINCT ON with the ROW_NUMBER() window function.
+//
+// SELECT dst.<columns> FROM (
+//   SELECT <columns>, <orders>, ROW_NUMBER() OVER (PARTITION BY <distinct on> ORDER BY <orders>) AS DST_ROW
+//   FROM ...
+// ) dst WHERE dst.DST_ROW = 1 ORDER BY dst.<orders>
+func (g *GenericTranslator) distinctOnSql(query *db.Query, proc QueryProcessor) string {
+	on, err := Translate(g.overrider.Translate, db.QUERY, query.GetDistinctOn()...)
+	if err != nil {
+		return ""
+	}
+
+	outer := tk.NewJoiner(", ")
+	for k, token := range query.Columns {
+		outer.Add(DISTINCT_ON_ALIAS + "." + g.overrider.ColumnAlias(token, k+1))
+	}
+
+	inner := tk.NewJoiner(", ")
+	inner.Add(proc.ColumnPart())
+	var window, outerOrders []string
+	for k, order := range query.GetOrders() {
+		dir := " ASC"
+		if !order.IsAsc() {
+			dir = " DESC"
+		}
+
+		expr, err := g.orderExpression(query, order)
+		if err != nil {
+			return ""
+		}
+		if expr == "" {
+			// the order refers to a column alias, that is also available outside
+			outerOrders = append(outerOrders, DISTINCT_ON_ALIAS+"."+order.GetAlias()+dir)
+			continue
+		}
+		alias := DISTINCT_ON_ORD_ALIAS + strconv.Itoa(k+1)
+		inner.Add(expr + " AS " + alias)
+		window = append(window, expr+dir)
+		outerOrders = append(outerOrders, DISTINCT_ON_ALIAS+"."+alias+dir)
+	}
+
+	over := "PARTITION BY " + strings.Join(on, ", ")
+	if len(window) != 0 {
+		over += " ORDER BY " + strings.Join(window, ", ")
+	}
+	inner.Add("ROW_NUMBER() OVER (" + over + ") AS " + DISTINCT_ON_ROW_ALIAS)
+
 	sel := tk.NewStrBuffer()
-	sel.Add("SELECT ")
-	if query.IsDistinct() {
-		sel.Add("DISTINCT ")
+	sel.Add("SELECT ", outer.String(),
+		" FROM (", SelectSql(query, proc, inner.String(), ""), ") ", DISTINCT_ON_ALIAS,
+		" WHERE ", DISTINCT_ON_ALIAS, ".", DISTINCT_ON_ROW_ALIAS, " = 1")
+	if len(outerOrders) != 0 {
+		sel.Add(" ORDER BY ", strings.Join(outerOrders, ", "))
 	}
-	sel.Add(proc.ColumnPart())
+	return sel.String()
+}
+
+// orderExpression returns the expression of the order.
+// If the order refers to a column alias, the expression of that column is returned, or empty if not found.
+func (g *GenericTranslator) orderExpression(query *db.Query, order *db.Order) (string, error) {
+	var token db.Tokener
+	if holder := order.GetHolder(); holder != nil {
+		token = holder
+	} else {
+		for k, column := range query.Columns {
+			if column.GetAlias() == order.GetAlias() || g.overrider.ColumnAlias(column, k+1) == order.GetAlias() {
+				token = column
+				break
+			}
+		}
+		if token == nil {
+			return "", nil
+		}
+	}
+	s, err := g.overrider.Translate(db.QUERY, token)
+	if err != nil {
+		return "", faults.Wrap(err)
+	}
+	return s, nil
+}
+
+// SelectSql assembles the SELECT statement with the parts of the query processor,
+// using the supplied columns and orders
+func SelectSql(query *db.Query, proc QueryProcessor, columns string, orders string) string {
+	// SELECT COLUNAS
+	sel := tk.NewStrBuffer()
+	sel.Add("SELECT ", columns)
 	// FROM
 	sel.Add(" FROM ", proc.FromPart())
 	// JOINS
@@ -1138,13 +1303,11 @@ func (g *GenericTranslator) GetSqlForQuery(query *db.Query) string {
 		sel.Add(proc.UnionPart())
 	}
 	// ORDER
-	if len(query.GetOrders()) != 0 {
-		sel.Add(" ORDER BY ", proc.OrderPart())
+	if orders != "" {
+		sel.Add(" ORDER BY ", orders)
 	}
 
-	sql := g.overrider.PaginateSQL(query, sel.String())
-
-	return sql
+	return sel.String()
 }
 
 func (g *GenericTranslator) PaginateSQL(query *db.Query, sql string) string {
@@ -1259,8 +1422,129 @@ func (g *GenericTranslator) GetConverter(name string) db.Converter {
 	return g.converters[name]
 }
 
+// IsRetryable returns false. Each dialect knows its own retryable errors.
+func (g *GenericTranslator) IsRetryable(err error) bool {
+	return false
+}
+
+// BindValue returns the value unchanged. Dialects whose drivers need specific bind types override it.
+func (g *GenericTranslator) BindValue(column *db.Column, value interface{}) (interface{}, error) {
+	return value, nil
+}
+
+// decimalValue returns the floating point value of a DECIMAL column as a string with the declared scale,
+// so that no precision is lost when the driver binds it.
+func decimalValue(column *db.Column, value interface{}) interface{} {
+	typ := column.GetType()
+	if typ == nil || typ.Kind != db.TYPE_DECIMAL {
+		return value
+	}
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', typ.Scale, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', typ.Scale, 32)
+	}
+	return value
+}
+
+// BULK LOAD
+
+// BulkLoader loads the rows supplied by the reader into the columns of the table,
+// using the translator for the table and column names
+type BulkLoader func(translator db.Translator, store db.IDb, table *db.Table, columns []*db.Column, reader db.ValuesReader) (int64, error)
+
+// BulkLoad uses the BulkLoader, if set, or inserts the rows one by one, reusing the same prepared statement.
+func (g *GenericTranslator) BulkLoad(store db.IDb, table *db.Table, columns []*db.Column, reader db.ValuesReader) (int64, error) {
+	if g.BulkLoader != nil {
+		return g.BulkLoader(g.overrider, store, table, columns, reader)
+	}
+
+	ctx := store.GetContext()
+	sql := g.BulkInsertSql(table, columns)
+	exec := store.GetConnection().ExecContext
+	if preparer, ok := store.GetConnection().(dbx.IPreparer); ok {
+		stmt, err := preparer.PrepareContext(ctx, sql)
+		if err != nil {
+			return 0, faults.Errorf("preparing statement\nSQL: %s: %w", sql, err)
+		}
+		defer stmt.Close()
+		exec = func(ctx context.Context, _ string, args ...interface{}) (gosql.Result, error) {
+			return stmt.ExecContext(ctx, args...)
+		}
+	}
+
+	var affected int64
+	for {
+		values, err := reader()
+		if err != nil {
+			return affected, faults.Wrap(err)
+		}
+		if values == nil {
+			return affected, nil
+		}
+		result, err := exec(ctx, sql, values...)
+		if err != nil {
+			return affected, faults.Errorf("executing statement\nSQL: %s\nParameters: %v: %w", sql, values, err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return affected, faults.Wrap(err)
+		}
+		affected += n
+	}
+}
+
+// BulkInsertSql returns the INSERT statement, with positional placeholders, for the columns of the table
+func (g *GenericTranslator) BulkInsertSql(table *db.Table, columns []*db.Column) string {
+	cols := tk.NewJoiner(", ")
+	vals := tk.NewJoiner(", ")
+	for k, column := range columns {
+		cols.Add(g.overrider.ColumnName(column))
+		vals.Add(g.overrider.GetPlaceholder(k, column.GetAlias()))
+	}
+	return "INSERT INTO " + g.overrider.TableName(table) + "(" + cols.String() + ") VALUES(" + vals.String() + ")"
+}
+
 // CONDITIONS
 
 //	func (this *GenericTranslator) String autoNumber(token db.Tokener) {
 //		throw new UnsupportedOperationException();
 //	}
+
+var groupingNames = map[db.GroupingType]string{
+	db.GROUPING_ROLLUP: "ROLLUP",
+	db.GROUPING_CUBE:   "CUBE",
+	db.GROUPING_SETS:   "GROUPING SETS",
+}
+
+// checkGrouping returns *db.NotSupportedFail if the query uses one of the groupings, or the GROUPING function,
+// that are not supported by the dialect
+func checkGrouping(dialect string, query *db.Query, groupings ...db.GroupingType) error {
+	for _, grouping := range groupings {
+		if query.GetGrouping() == grouping {
+			return &db.NotSupportedFail{Feature: groupingNames[grouping], Dialect: dialect}
+		}
+	}
+	tokens := append([]db.Tokener{}, query.Columns...)
+	if having := query.GetHaving(); having != nil {
+		tokens = append(tokens, having)
+	}
+	if usesOperator(db.TOKEN_GROUPING, tokens...) {
+		return &db.NotSupportedFail{Feature: db.TOKEN_GROUPING, Dialect: dialect}
+	}
+	return nil
+}
+
+// usesOperator reports if any of the tokens, or of their members, has the operator
+func usesOperator(operator string, tokens ...db.Tokener) bool {
+	for _, token := range tokens {
+		if token == nil || token.IsNil() {
+			continue
+		}
+		if token.GetOperator() == operator || usesOperator(operator, token.GetMembers()...) {
+			return true
+		}
+	}
+	return false
+}
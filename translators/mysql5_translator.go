@@ -1,23 +1,31 @@
 package translators
 
 import (
+	"errors"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/quintans/faults"
 	"github.com/quintans/goSQL/db"
 	tk "github.com/quintans/toolkit"
-
-	"strings"
 )
 
+const MYSQL5_DIALECT = "MySQL 5"
+
 type MySQL5Translator struct {
 	*GenericTranslator
 }
 
-var _ db.Translator = &MySQL5Translator{}
+var (
+	_ db.Translator   = &MySQL5Translator{}
+	_ db.QueryChecker = &MySQL5Translator{}
+)
 
 func NewMySQL5Translator() *MySQL5Translator {
 	this := new(MySQL5Translator)
 	this.GenericTranslator = new(GenericTranslator)
 	this.Init(this)
-	this.QueryProcessorFactory = func() QueryProcessor { return NewQueryBuilder(this) }
+	this.QueryProcessorFactory = func() QueryProcessor { return NewMySQL5QueryBuilder(this) }
 	this.InsertProcessorFactory = func() InsertProcessor { return NewInsertBuilder(this) }
 	this.UpdateProcessorFactory = func() UpdateProcessor { return NewUpdateBuilder(this) }
 	this.DeleteProcessorFactory = func() DeleteProcessor { return NewMySQL5DeleteBuilder(this) }
@@ -25,6 +33,42 @@ func NewMySQL5Translator() *MySQL5Translator {
 	return this
 }
 
+// CheckQuery rejects the features that MySQL 5 does not support: CUBE, GROUPING SETS, the GROUPING function,
+// and DISTINCT ON, that is emulated with window functions
+func (m *MySQL5Translator) CheckQuery(query *db.Query) error {
+	if err := checkGrouping(MYSQL5_DIALECT, query, db.GROUPING_CUBE, db.GROUPING_SETS); err != nil {
+		return err
+	}
+	if len(query.GetDistinctOn()) != 0 {
+		return &db.NotSupportedFail{Feature: "DISTINCT ON", Dialect: MYSQL5_DIALECT}
+	}
+	return nil
+}
+
+func NewMySQL5QueryBuilder(translator db.Translator) *MySQL5QueryBuilder {
+	this := new(MySQL5QueryBuilder)
+	this.init(translator)
+	return this
+}
+
+type MySQL5QueryBuilder struct {
+	QueryBuilder
+}
+
+// Group uses the MySQL syntax for ROLLUP: GROUP BY a, b WITH ROLLUP
+func (m *MySQL5QueryBuilder) Group(query *db.Query) error {
+	if query.GetGrouping() != db.GROUPING_ROLLUP {
+		return m.QueryBuilder.Group(query)
+	}
+
+	args, err := m.groupTokens(query.GetGroupByTokens())
+	if err != nil {
+		return faults.Wrap(err)
+	}
+	m.groupPart.Add(strings.Join(args, ", ") + " WITH ROLLUP")
+	return nil
+}
+
 func NewMySQL5DeleteBuilder(translator db.Translator) *MySQL5DeleteBuilder {
 	this := new(MySQL5DeleteBuilder)
 	this.init(translator)
@@ -72,3 +116,66 @@ func (m *MySQL5Translator) PaginateSQL(query *db.Query, sql string) string {
 
 	return sql
 }
+
+// IsRetryable returns true for deadlocks (1213) and lock wait timeouts (1205)
+func (m *MySQL5Translator) IsRetryable(err error) bool {
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		return myErr.Number == 1213 || myErr.Number == 1205
+	}
+	return false
+}
+
+// DDL
+
+func (m *MySQL5Translator) ColumnTypeSql(column *db.Column) string {
+	switch ddlType(column).Kind {
+	case db.TYPE_TEXT:
+		return "LONGTEXT"
+	case db.TYPE_BINARY:
+		return "LONGBLOB"
+	case db.TYPE_DOUBLE:
+		return "DOUBLE"
+	case db.TYPE_TIMESTAMP:
+		// TIMESTAMP is limited to 2038
+		return "DATETIME"
+	}
+	return m.GenericTranslator.ColumnTypeSql(column)
+}
+
+func (m *MySQL5Translator) AutoKeySql(column *db.Column) string {
+	return m.ColumnTypeSql(column) + " AUTO_INCREMENT"
+}
+
+func (m *MySQL5Translator) TableOptionsSql(table *db.Table) string {
+	return " ENGINE=InnoDB DEFAULT CHARSET=utf8mb4"
+}
+
+func (m *MySQL5Translator) GetSqlForDropForeignKey(fk *db.ForeignKey) string {
+	return "ALTER TABLE " + m.TableName(fk.Table) + " DROP FOREIGN KEY " + constraintName(fk.Name)
+}
+
+// CATALOG
+
+func (m *MySQL5Translator) CatalogSchemaSql() string {
+	return "DATABASE()"
+}
+
+// GetSqlForCatalogColumns reads TINYINT(1) as boolean, since it is how MySQL declares BOOLEAN
+func (m *MySQL5Translator) GetSqlForCatalogColumns() string {
+	return "SELECT table_name, column_name, CASE WHEN column_type = 'tinyint(1)' THEN 'boolean' ELSE data_type END," +
+		" CASE WHEN is_nullable = 'YES' THEN 1 ELSE 0 END," +
+		" character_maximum_length, numeric_precision, numeric_scale" +
+		" FROM information_schema.columns" +
+		" WHERE table_schema = DATABASE()" +
+		" ORDER BY table_name, ordinal_position"
+}
+
+// GetSqlForCatalogForeignKeys uses the referenced columns of key_column_usage,
+// since all the primary keys are named PRIMARY in MySQL
+func (m *MySQL5Translator) GetSqlForCatalogForeignKeys() string {
+	return "SELECT table_name, constraint_name, column_name, referenced_table_name, referenced_column_name" +
+		" FROM information_schema.key_column_usage" +
+		" WHERE table_schema = DATABASE() AND referenced_table_name IS NOT NULL" +
+		" ORDER BY table_name, constraint_name, ordinal_position"
+}
@@ -0,0 +1,151 @@
+// Package mysqlbulk is the bulk load of MySQL with LOAD DATA LOCAL INFILE, that is only available with the go-sql-driver/mysql driver.
+//
+// ex:
+//
+//	translator := translators.NewMySQL5Translator()
+//	translator.BulkLoader = mysqlbulk.LoadData
+package mysqlbulk
+
+import (
+	"bufio"
+	"database/sql/driver"
+	"io"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/quintans/faults"
+	"github.com/quintans/goSQL/db"
+	"github.com/quintans/goSQL/translators"
+	tk "github.com/quintans/toolkit"
+)
+
+var _ translators.BulkLoader = LoadData
+
+var readerSeq int64
+
+// LoadData uses LOAD DATA LOCAL INFILE, streaming the rows through a registered reader handler.
+// The rows are written in the default format of LOAD DATA: tab separated fields and new line separated rows.
+// Time values are written in UTC, the default location of the driver.
+// The database must be opened with the go-sql-driver/mysql driver and the server must allow local_infile.
+func LoadData(translator db.Translator, store db.IDb, table *db.Table, columns []*db.Column, reader db.ValuesReader) (int64, error) {
+	conn, ok := store.GetConnection().(interface{ Driver() driver.Driver })
+	if !ok {
+		return 0, faults.Errorf("LOAD DATA LOCAL INFILE requires a connection with a driver. got %T", store.GetConnection())
+	}
+	if _, ok := conn.Driver().(*mysql.MySQLDriver); !ok {
+		return 0, faults.Errorf("LOAD DATA LOCAL INFILE requires the go-sql-driver/mysql driver. got %T", conn.Driver())
+	}
+
+	name := "goSQL_" + strconv.FormatInt(atomic.AddInt64(&readerSeq, 1), 10)
+
+	var done chan error
+	mysql.RegisterReaderHandler(name, func() io.Reader {
+		done = make(chan error, 1)
+		pr, pw := io.Pipe()
+		go func() {
+			err := writeRows(pw, reader)
+			done <- err
+			pw.CloseWithError(err)
+		}()
+		return pr
+	})
+	defer mysql.DeregisterReaderHandler(name)
+
+	cols := tk.NewJoiner(", ")
+	for _, column := range columns {
+		cols.Add(translator.ColumnName(column))
+	}
+	sql := "LOAD DATA LOCAL INFILE 'Reader::" + name + "' INTO TABLE " + translator.TableName(table) +
+		" CHARACTER SET utf8mb4 (" + cols.String() + ")"
+	result, err := store.GetConnection().ExecContext(store.GetContext(), sql)
+	if done != nil {
+		// the driver closes the reader, so the writer is always released
+		if rerr := <-done; rerr != nil {
+			return 0, faults.Wrap(rerr)
+		}
+	}
+	if err != nil {
+		return 0, faults.Errorf("executing statement\nSQL: %s: %w", sql, err)
+	}
+	affected, err := result.RowsAffected()
+	return affected, faults.Wrap(err)
+}
+
+func writeRows(w io.Writer, reader db.ValuesReader) error {
+	buf := bufio.NewWriter(w)
+	var line []byte
+	for {
+		values, err := reader()
+		if err != nil {
+			return faults.Wrap(err)
+		}
+		if values == nil {
+			break
+		}
+		line = line[:0]
+		for k, v := range values {
+			if k > 0 {
+				line = append(line, '\t')
+			}
+			line, err = appendField(line, v)
+			if err != nil {
+				return faults.Wrap(err)
+			}
+		}
+		line = append(line, '\n')
+		if _, err := buf.Write(line); err != nil {
+			return faults.Wrap(err)
+		}
+	}
+	return faults.Wrap(buf.Flush())
+}
+
+func appendField(buf []byte, value interface{}) ([]byte, error) {
+	v, err := driver.DefaultParameterConverter.ConvertValue(value)
+	if err != nil {
+		return nil, faults.Wrap(err)
+	}
+	switch t := v.(type) {
+	case nil:
+		return append(buf, `\N`...), nil
+	case int64:
+		return strconv.AppendInt(buf, t, 10), nil
+	case float64:
+		return strconv.AppendFloat(buf, t, 'g', -1, 64), nil
+	case bool:
+		if t {
+			return append(buf, '1'), nil
+		}
+		return append(buf, '0'), nil
+	case time.Time:
+		return t.UTC().AppendFormat(buf, "2006-01-02 15:04:05.999999"), nil
+	case []byte:
+		return appendEscaped(buf, string(t)), nil
+	case string:
+		return appendEscaped(buf, t), nil
+	default:
+		return nil, faults.Errorf("unsupported bulk load value type %T", v)
+	}
+}
+
+func appendEscaped(buf []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			buf = append(buf, '\\', '\\')
+		case '\t':
+			buf = append(buf, '\\', 't')
+		case '\n':
+			buf = append(buf, '\\', 'n')
+		case '\r':
+			buf = append(buf, '\\', 'r')
+		case 0:
+			buf = append(buf, '\\', '0')
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return buf
+}
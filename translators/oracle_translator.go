@@ -1,8 +1,13 @@
 package translators
 
 import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
 	"strconv"
 
+	"github.com/quintans/faults"
 	"github.com/quintans/goSQL/db"
 
 	"fmt"
@@ -56,6 +61,237 @@ func (o *OracleTranslator) PaginateSQL(query *db.Query, sql string) string {
 	return sql
 }
 
+// GetSqlForRelease returns an empty statement because Oracle does not release savepoints
+func (o *OracleTranslator) GetSqlForRelease(name string) string {
+	return ""
+}
+
 func (o *OracleTranslator) GetPlaceholder(index int, name string) string {
 	return ":" + strconv.Itoa(index+1)
 }
+
+// IsRetryable returns true for deadlocks (ORA-00060) and serialization failures (ORA-08177).
+// The driver is not imported, so the error is matched by its Code method.
+func (o *OracleTranslator) IsRetryable(err error) bool {
+	var oraErr interface{ Code() int }
+	if errors.As(err, &oraErr) {
+		return oraErr.Code() == 60 || oraErr.Code() == 8177
+	}
+	return false
+}
+
+// BindValue binds BOOLEAN columns as 1 or 0, since they are mapped to NUMBER(1),
+// and floating point values of DECIMAL columns as strings.
+func (o *OracleTranslator) BindValue(column *db.Column, value interface{}) (interface{}, error) {
+	if typ := column.GetType(); typ != nil && typ.Kind == db.TYPE_BOOLEAN {
+		if b, ok := value.(bool); ok {
+			if b {
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	return decimalValue(column, value), nil
+}
+
+// BULK LOAD
+
+// oracleBulkSize is the number of rows sent in each array binding execution
+const oracleBulkSize = 1000
+
+// BulkLoad uses array binding, executing the INSERT with one slice of values per column,
+// for each block of oracleBulkSize rows.
+// Keys generated by sequences are not fetched, so key values must be supplied or be generated by the database.
+func (o *OracleTranslator) BulkLoad(store db.IDb, table *db.Table, columns []*db.Column, reader db.ValuesReader) (int64, error) {
+	sql := o.BulkInsertSql(table, columns)
+
+	var affected int64
+	rows := make([][]interface{}, 0, oracleBulkSize)
+	flush := func() error {
+		if len(rows) == 0 {
+			return nil
+		}
+		args := make([]interface{}, len(columns))
+		for k := range columns {
+			arr, err := oracleArray(rows, k)
+			if err != nil {
+				return faults.Wrap(err)
+			}
+			args[k] = arr
+		}
+		result, err := store.GetConnection().ExecContext(store.GetContext(), sql, args...)
+		if err != nil {
+			return faults.Errorf("executing statement\nSQL: %s: %w", sql, err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return faults.Wrap(err)
+		}
+		affected += n
+		rows = rows[:0]
+		return nil
+	}
+
+	for {
+		values, err := reader()
+		if err != nil {
+			return affected, faults.Wrap(err)
+		}
+		if values == nil {
+			break
+		}
+		rows = append(rows, values)
+		if len(rows) == oracleBulkSize {
+			if err := flush(); err != nil {
+				return affected, err
+			}
+		}
+	}
+	err := flush()
+	return affected, err
+}
+
+// oracleArray builds a typed slice with the values of a column, as required by the driver for array binding
+func oracleArray(rows [][]interface{}, column int) (interface{}, error) {
+	values := make([]interface{}, len(rows))
+	var typ reflect.Type
+	hasNil := false
+	for k, row := range rows {
+		v, err := driver.DefaultParameterConverter.ConvertValue(row[column])
+		if err != nil {
+			return nil, faults.Wrap(err)
+		}
+		if b, ok := v.(bool); ok {
+			// there is no boolean type in Oracle
+			if b {
+				v = int64(1)
+			} else {
+				v = int64(0)
+			}
+		}
+		if v == nil {
+			hasNil = true
+		} else if typ == nil {
+			typ = reflect.TypeOf(v)
+		} else if typ != reflect.TypeOf(v) {
+			return nil, faults.Errorf("column %d has values of different types: %s and %T", column, typ, v)
+		}
+		values[k] = v
+	}
+
+	switch {
+	case typ == nil:
+		// empty strings are NULL in Oracle
+		return make([]string, len(rows)), nil
+	case typ.Kind() == reflect.Int64 && hasNil:
+		arr := make([]sql.NullInt64, len(values))
+		for k, v := range values {
+			if v != nil {
+				arr[k] = sql.NullInt64{Int64: v.(int64), Valid: true}
+			}
+		}
+		return arr, nil
+	case typ.Kind() == reflect.Float64 && hasNil:
+		arr := make([]sql.NullFloat64, len(values))
+		for k, v := range values {
+			if v != nil {
+				arr[k] = sql.NullFloat64{Float64: v.(float64), Valid: true}
+			}
+		}
+		return arr, nil
+	}
+
+	arr := reflect.MakeSlice(reflect.SliceOf(typ), len(values), len(values))
+	for k, v := range values {
+		if v != nil {
+			arr.Index(k).Set(reflect.ValueOf(v))
+		}
+	}
+	return arr.Interface(), nil
+}
+
+// DDL
+
+func (o *OracleTranslator) ColumnTypeSql(column *db.Column) string {
+	typ := ddlType(column)
+	switch typ.Kind {
+	case db.TYPE_VARCHAR:
+		return fmt.Sprintf("VARCHAR2(%d)", typ.Length)
+	case db.TYPE_SMALLINT:
+		return "NUMBER(5)"
+	case db.TYPE_INTEGER:
+		return "NUMBER(10)"
+	case db.TYPE_BIGINT:
+		return "NUMBER(19)"
+	case db.TYPE_DECIMAL:
+		return fmt.Sprintf("NUMBER(%d,%d)", typ.Precision, typ.Scale)
+	case db.TYPE_DOUBLE:
+		return "BINARY_DOUBLE"
+	case db.TYPE_BOOLEAN:
+		return "NUMBER(1)"
+	}
+	return o.GenericTranslator.ColumnTypeSql(column)
+}
+
+// GetSqlForCreateAutoKey creates the sequence used by GetAutoNumberQuery
+func (o *OracleTranslator) GetSqlForCreateAutoKey(table *db.Table) []string {
+	if column := table.GetSingleKeyColumn(); column != nil && isAutoKey(column) {
+		return []string{"CREATE SEQUENCE " + strings.ToUpper(table.GetName()) + "_SEQ"}
+	}
+	return nil
+}
+
+func (o *OracleTranslator) GetSqlForDropAutoKey(table *db.Table) []string {
+	if column := table.GetSingleKeyColumn(); column != nil && isAutoKey(column) {
+		return []string{"DROP SEQUENCE " + strings.ToUpper(table.GetName()) + "_SEQ"}
+	}
+	return nil
+}
+
+// CATALOG
+
+func (o *OracleTranslator) GetSqlForCatalogColumns() string {
+	return "SELECT TABLE_NAME, COLUMN_NAME, DATA_TYPE, CASE WHEN NULLABLE = 'Y' THEN 1 ELSE 0 END," +
+		" CHAR_LENGTH, DATA_PRECISION, DATA_SCALE" +
+		" FROM ALL_TAB_COLUMNS" +
+		" WHERE OWNER = USER" +
+		" ORDER BY TABLE_NAME, COLUMN_ID"
+}
+
+func (o *OracleTranslator) GetSqlForCatalogKeys() string {
+	return "SELECT c.TABLE_NAME, cc.COLUMN_NAME" +
+		" FROM ALL_CONSTRAINTS c" +
+		" JOIN ALL_CONS_COLUMNS cc ON cc.OWNER = c.OWNER AND cc.CONSTRAINT_NAME = c.CONSTRAINT_NAME" +
+		" WHERE c.OWNER = USER AND c.CONSTRAINT_TYPE = 'P'" +
+		" ORDER BY c.TABLE_NAME, cc.POSITION"
+}
+
+func (o *OracleTranslator) GetSqlForCatalogForeignKeys() string {
+	return "SELECT c.TABLE_NAME, c.CONSTRAINT_NAME, cc.COLUMN_NAME, rc.TABLE_NAME, rcc.COLUMN_NAME" +
+		" FROM ALL_CONSTRAINTS c" +
+		" JOIN ALL_CONS_COLUMNS cc ON cc.OWNER = c.OWNER AND cc.CONSTRAINT_NAME = c.CONSTRAINT_NAME" +
+		" JOIN ALL_CONSTRAINTS rc ON rc.OWNER = c.R_OWNER AND rc.CONSTRAINT_NAME = c.R_CONSTRAINT_NAME" +
+		" JOIN ALL_CONS_COLUMNS rcc ON rcc.OWNER = rc.OWNER AND rcc.CONSTRAINT_NAME = rc.CONSTRAINT_NAME AND rcc.POSITION = cc.POSITION" +
+		" WHERE c.OWNER = USER AND c.CONSTRAINT_TYPE = 'R'" +
+		" ORDER BY c.TABLE_NAME, c.CONSTRAINT_NAME, cc.POSITION"
+}
+
+// CatalogType reads the integer NUMBER types, as declared by ColumnTypeSql, back into their column types.
+// NUMBER without precision is read as BIGINT.
+func (o *OracleTranslator) CatalogType(dataType string, length, precision, scale int) db.ColumnType {
+	if strings.EqualFold(dataType, "NUMBER") && scale == 0 {
+		switch {
+		case precision == 0:
+			return db.Bigint()
+		case precision == 1:
+			return db.Boolean()
+		case precision <= 5:
+			return db.Smallint()
+		case precision <= 10:
+			return db.Integer()
+		case precision <= 19:
+			return db.Bigint()
+		}
+	}
+	return o.GenericTranslator.CatalogType(dataType, length, precision, scale)
+}
@@ -0,0 +1,60 @@
+// Package pgbulk is the bulk load of PostgreSQL with COPY FROM STDIN, that is only available with the lib/pq driver.
+//
+// ex:
+//
+//	translator := translators.NewPostgreSQLTranslator()
+//	translator.BulkLoader = pgbulk.CopyIn
+package pgbulk
+
+import (
+	"github.com/lib/pq"
+	"github.com/quintans/faults"
+	"github.com/quintans/goSQL/db"
+	"github.com/quintans/goSQL/translators"
+)
+
+var _ translators.BulkLoader = CopyIn
+
+// CopyIn uses COPY FROM STDIN. It must run inside a transaction of a database opened with the lib/pq driver.
+func CopyIn(translator db.Translator, store db.IDb, table *db.Table, columns []*db.Column, reader db.ValuesReader) (int64, error) {
+	tx, ok := store.GetConnection().(*db.MyTx)
+	if !ok {
+		return 0, faults.New("COPY FROM STDIN must run inside a transaction")
+	}
+	if _, ok := tx.Driver().(*pq.Driver); !ok {
+		return 0, faults.Errorf("COPY FROM STDIN requires the lib/pq driver. got %T", tx.Driver())
+	}
+
+	cols := make([]string, len(columns))
+	for k, column := range columns {
+		cols[k] = translator.ColumnName(column)
+	}
+	ctx := store.GetContext()
+	sql := pq.CopyIn(translator.TableName(table), cols...)
+	stmt, err := tx.PrepareContext(ctx, sql)
+	if err != nil {
+		return 0, faults.Errorf("preparing statement\nSQL: %s: %w", sql, err)
+	}
+	defer stmt.Close()
+
+	for {
+		values, err := reader()
+		if err != nil {
+			return 0, faults.Wrap(err)
+		}
+		if values == nil {
+			break
+		}
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			return 0, faults.Errorf("copying row\nSQL: %s\nParameters: %v: %w", sql, values, err)
+		}
+	}
+
+	// flushes the buffered rows
+	result, err := stmt.ExecContext(ctx)
+	if err != nil {
+		return 0, faults.Errorf("executing statement\nSQL: %s: %w", sql, err)
+	}
+	affected, err := result.RowsAffected()
+	return affected, faults.Wrap(err)
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/quintans/goSQL/db"
 	tk "github.com/quintans/toolkit"
 
+	"errors"
 	"strconv"
 	"strings"
 )
@@ -52,6 +53,35 @@ func (o *PostgreSQLTranslator) GetSqlForInsert(insert *db.Insert) string {
 	return sql
 }
 
+// QUERY
+func (o *PostgreSQLTranslator) GetSqlForQuery(query *db.Query) string {
+	if len(query.GetDistinctOn()) == 0 {
+		return o.GenericTranslator.GetSqlForQuery(query)
+	}
+
+	// native DISTINCT ON
+	proc := o.CreateQueryProcessor(query)
+	on, err := Translate(o.Translate, db.QUERY, query.GetDistinctOn()...)
+	if err != nil {
+		return ""
+	}
+	columns := "DISTINCT ON (" + strings.Join(on, ", ") + ") " + proc.ColumnPart()
+	sql := SelectSql(query, proc, columns, proc.OrderPart())
+
+	return o.PaginateSQL(query, sql)
+}
+
+// IsRetryable returns true for serialization failures (40001) and deadlocks (40P01).
+// The SQLSTATE is read from the errors of the drivers, like lib/pq and pgx, that report it with SQLState().
+func (o *PostgreSQLTranslator) IsRetryable(err error) bool {
+	var stateErr interface{ SQLState() string }
+	if errors.As(err, &stateErr) {
+		state := stateErr.SQLState()
+		return state == "40001" || state == "40P01"
+	}
+	return false
+}
+
 func (o *PostgreSQLTranslator) TableName(table *db.Table) string {
 	return strings.ToLower(table.GetName())
 }
@@ -104,3 +134,27 @@ func (p *PostgreSQLTranslator) PaginateSQL(query *db.Query, sql string) string {
 
 	return sql
 }
+
+// DDL
+
+func (o *PostgreSQLTranslator) ColumnTypeSql(column *db.Column) string {
+	switch ddlType(column).Kind {
+	case db.TYPE_TEXT:
+		return "TEXT"
+	case db.TYPE_BINARY:
+		return "BYTEA"
+	}
+	return o.GenericTranslator.ColumnTypeSql(column)
+}
+
+// AutoKeySql uses the serial types, creating a sequence owned by the column
+func (o *PostgreSQLTranslator) AutoKeySql(column *db.Column) string {
+	switch ddlType(column).Kind {
+	case db.TYPE_SMALLINT:
+		return "SMALLSERIAL"
+	case db.TYPE_INTEGER:
+		return "SERIAL"
+	default:
+		return "BIGSERIAL"
+	}
+}